@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/KashifKhn/remove-comments/cli/internal/config"
+	"github.com/KashifKhn/remove-comments/cli/internal/walker"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect .remove-comments.yaml/.toml configuration",
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show [file]",
+	Short: "Print the effective merged configuration for a file",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runConfigShow,
+}
+
+func init() {
+	configCmd.AddCommand(configShowCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+func runConfigShow(cmd *cobra.Command, args []string) error {
+	target := "."
+	if len(args) == 1 {
+		target = args[0]
+	}
+	if _, err := os.Stat(target); err != nil {
+		return err
+	}
+
+	finder, err := newFinder()
+	if err != nil {
+		return err
+	}
+	stack, err := finder.Stack(target)
+	if err != nil {
+		return err
+	}
+	if err := stack.Apply(); err != nil {
+		return err
+	}
+	mappings, err := walker.ParseMappings(stack.Base(config.Settings{}).Map)
+	if err != nil {
+		return err
+	}
+	lang, reason := "", ""
+	if cfg, why, ok := walker.Detect(target, target, false, mappings); ok {
+		lang, reason = cfg.Name, why
+	}
+	return stack.Show(cmd.OutOrStdout(), target, lang, reason)
+}
@@ -4,15 +4,19 @@ import (
 	"fmt"
 	"os"
 	"runtime"
+	"sort"
 	"sync"
 	"sync/atomic"
 
 	"github.com/spf13/cobra"
 
+	"github.com/KashifKhn/remove-comments/cli/internal/config"
 	"github.com/KashifKhn/remove-comments/cli/internal/diff"
+	"github.com/KashifKhn/remove-comments/cli/internal/keep"
 	"github.com/KashifKhn/remove-comments/cli/internal/output"
 	"github.com/KashifKhn/remove-comments/cli/internal/parser"
 	"github.com/KashifKhn/remove-comments/cli/internal/remover"
+	"github.com/KashifKhn/remove-comments/cli/internal/verify"
 	"github.com/KashifKhn/remove-comments/cli/internal/walker"
 )
 
@@ -27,10 +31,25 @@ var (
 	flagWrite       bool
 	flagQuiet       bool
 	flagDiff        bool
+	flagVerbose     bool
 	flagLang        string
 	flagJobs        int
 	flagMaxFileSize int64
 	flagExclude     []string
+	flagMap         []string
+	flagKeep        []string
+	flagKeepFile    []string
+	flagKeepLicense bool
+	flagStripMagic  bool
+	flagStripMarks  bool
+	flagSuppress    string
+	flagOnly        []string
+	flagSkip        []string
+	flagBlankLines  string
+	flagMaxBlank    int
+	flagPreserveLn  bool
+	flagPreserveCol bool
+	flagConfig      string
 )
 
 func Execute(version string) {
@@ -42,13 +61,29 @@ func Execute(version string) {
 }
 
 func init() {
+	defaults := config.Default()
 	rootCmd.Flags().BoolVarP(&flagWrite, "write", "w", false, "Write changes to disk (default is dry-run)")
 	rootCmd.Flags().BoolVarP(&flagQuiet, "quiet", "q", false, "Print only the final summary line")
 	rootCmd.Flags().BoolVarP(&flagDiff, "diff", "d", false, "Show unified diff for each changed file")
+	rootCmd.Flags().BoolVar(&flagVerbose, "verbose", false, "Print the detected language of every file, and why it was chosen")
 	rootCmd.Flags().StringVar(&flagLang, "lang", "", "Only process files of this language (e.g. go, python)")
 	rootCmd.Flags().IntVarP(&flagJobs, "jobs", "j", 0, "Number of parallel workers (default: NumCPU)")
-	rootCmd.Flags().Int64Var(&flagMaxFileSize, "max-file-size", 10*1024*1024, "Skip files larger than this size in bytes")
+	rootCmd.Flags().Int64Var(&flagMaxFileSize, "max-file-size", defaults.MaxFileSize, "Skip files larger than this size in bytes")
 	rootCmd.Flags().StringArrayVarP(&flagExclude, "exclude", "e", nil, "Glob patterns to exclude (e.g. '*.g.dart', 'vendor/**')")
+	rootCmd.Flags().StringArrayVar(&flagMap, "map", nil, "Force a language for files matching a glob, as GLOB=LANG (e.g. 'bin/*=bash')")
+	rootCmd.Flags().StringArrayVarP(&flagKeep, "keep", "k", nil, "Keep comments whose text matches this regex (e.g. 'TODO|FIXME')")
+	rootCmd.Flags().StringArrayVar(&flagKeepFile, "keep-file", nil, "Read keep regexes from a file, one per line")
+	rootCmd.Flags().BoolVar(&flagKeepLicense, "keep-license", false, "Keep the leading license/copyright header comment of each file")
+	rootCmd.Flags().BoolVar(&flagStripMagic, "strip-magic", false, "Also remove shebangs, encoding declarations and editor modelines")
+	rootCmd.Flags().BoolVar(&flagStripMarks, "strip-markers", false, "Remove comments that hold nothing but an rmc:keep, rmc:off or rmc:on marker")
+	rootCmd.Flags().StringVar(&flagSuppress, "suppressions", defaults.Suppressions, "Lint/type-checker suppression comments: keep, remove or list")
+	rootCmd.Flags().StringSliceVar(&flagOnly, "only", nil, "Only remove comments of these kinds (line, block, doc, directive, license, shebang)")
+	rootCmd.Flags().StringSliceVar(&flagSkip, "skip", nil, "Never remove comments of these kinds (e.g. --skip doc)")
+	rootCmd.Flags().StringVar(&flagBlankLines, "blank-lines", defaults.BlankLines, "Blank lines left by removal: preserve, collapse or trim")
+	rootCmd.Flags().IntVar(&flagMaxBlank, "max-blank-lines", defaults.MaxBlankLines, "Blank lines to keep in a row with --blank-lines collapse")
+	rootCmd.Flags().BoolVar(&flagPreserveLn, "preserve-lines", false, "Leave an empty line where a comment line was removed so line numbers do not shift")
+	rootCmd.Flags().BoolVar(&flagPreserveCol, "preserve-columns", false, "Replace comment bytes with spaces so line and column numbers do not shift")
+	rootCmd.PersistentFlags().StringVar(&flagConfig, "config", "", "Config file applied on top of any .remove-comments.yaml/.toml found above the target")
 }
 
 func run(cmd *cobra.Command, args []string) error {
@@ -61,25 +96,75 @@ func run(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	finder, err := newFinder()
+	if err != nil {
+		return err
+	}
+	stack, err := finder.Stack(root)
+	if err != nil {
+		return err
+	}
+	if err := stack.Apply(); err != nil {
+		return err
+	}
+	cli := flagSettings(cmd)
+	profiles := newProfiles(finder, cli)
+	if err := profiles.check(stack); err != nil {
+		return err
+	}
+
 	jobs := flagJobs
 	if jobs <= 0 {
 		jobs = runtime.NumCPU()
 	}
 
-	entries, walkErrs := walker.Walk(root, flagLang, flagMaxFileSize, flagExclude)
+	base := stack.Base(cli)
+	mappings, err := walker.ParseMappings(base.Map)
+	if err != nil {
+		return err
+	}
+	var scopeErr error
+	warned := map[string]bool{}
+	entries, walkErrs := walker.WalkWith(root, base.Lang, base.MaxFileSize, base.Exclude, mappings, func(path string) (int64, bool) {
+		fileStack, err := finder.Stack(path)
+		if err != nil {
+			scopeErr = err
+			return 0, false
+		}
+		limit, ok, err := fileStack.Scope(stack, path, cli)
+		if err != nil && !warned[err.Error()] {
+			warned[err.Error()] = true
+			fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+		}
+		return limit, ok
+	})
 	if len(walkErrs) > 0 {
 		for _, e := range walkErrs {
 			fmt.Fprintf(os.Stderr, "walk error: %v\n", e)
 		}
 	}
+	if scopeErr != nil {
+		return scopeErr
+	}
+	for _, entry := range entries {
+		fileStack, err := finder.Stack(entry.Path)
+		if err != nil {
+			return err
+		}
+		if err := profiles.check(fileStack); err != nil {
+			return err
+		}
+	}
 
-	printer := output.New(os.Stdout, flagQuiet, flagWrite, flagDiff)
+	printer := output.New(os.Stdout, flagQuiet, flagWrite, flagDiff, flagVerbose)
 
 	var (
-		mu      sync.Mutex
-		changed int32
-		errors  int32
-		total   int32
+		mu        sync.Mutex
+		changed   int32
+		kept      int32
+		protected int32
+		errors    int32
+		total     int32
 	)
 
 	work := make(chan walker.FileEntry, jobs*2)
@@ -91,6 +176,9 @@ func run(cmd *cobra.Command, args []string) error {
 			defer wg.Done()
 			for entry := range work {
 				atomic.AddInt32(&total, 1)
+				mu.Lock()
+				printer.Language(entry.Path, entry.Lang.Name, entry.Reason)
+				mu.Unlock()
 
 				src, err := os.ReadFile(entry.Path)
 				if err != nil {
@@ -110,10 +198,46 @@ func run(cmd *cobra.Command, args []string) error {
 					continue
 				}
 
-				after := remover.Remove(src, ranges)
+				prof, err := profiles.get(entry.Path, entry.Lang.Name)
+				if err != nil {
+					atomic.AddInt32(&errors, 1)
+					mu.Lock()
+					printer.Error(entry.Path, err)
+					mu.Unlock()
+					continue
+				}
+				keepRules := prof.rules
+
+				ranges, keptRanges := keepRules.Split(src, ranges)
+				for _, r := range keptRanges {
+					if r.Protection != parser.Unprotected {
+						atomic.AddInt32(&protected, 1)
+					} else {
+						atomic.AddInt32(&kept, 1)
+					}
+					if r.Protection == parser.ProtectSuppression && keepRules.Suppressions == keep.SuppressionsList {
+						mu.Lock()
+						printer.Suppression(entry.Path, int(r.StartRow)+1, string(r.Text(src)))
+						mu.Unlock()
+					}
+				}
+
+				after, stats := remover.RemoveWith(src, ranges, prof.opts)
 				result := diff.Compute(entry.Path, src, after)
+				result.BlankLines = stats.BlankLines
+				result.ClearedLines = stats.ClearedLines
+				for _, r := range keptRanges {
+					result.MarkKept(int(r.StartRow), int(r.EndRow))
+				}
 
 				if result.Changed {
+					if verifyErr := verify.Check(src, after, entry.Lang); verifyErr != nil {
+						atomic.AddInt32(&errors, 1)
+						mu.Lock()
+						printer.Error(entry.Path, verifyErr)
+						mu.Unlock()
+						continue
+					}
 					if flagWrite {
 						info, statErr := os.Stat(entry.Path)
 						if statErr != nil {
@@ -153,6 +277,200 @@ func run(cmd *cobra.Command, args []string) error {
 	close(work)
 	wg.Wait()
 
-	printer.Summary(int(changed), 0, int(errors), int(total))
+	printer.Summary(output.Totals{
+		Changed:   int(changed),
+		Kept:      int(kept),
+		Protected: int(protected),
+		Errors:    int(errors),
+		Total:     int(total),
+	})
+	return nil
+}
+
+func newFinder() (*config.Finder, error) {
+	finder := &config.Finder{}
+	if flagConfig != "" {
+		cfg, err := config.Load(flagConfig)
+		if err != nil {
+			return nil, err
+		}
+		finder.Extra = cfg
+	}
+	return finder, nil
+}
+
+func flagSettings(cmd *cobra.Command) config.Settings {
+	changed := cmd.Flags().Changed
+	var s config.Settings
+	if changed("exclude") {
+		s.Exclude = flagExclude
+	}
+	if changed("map") {
+		s.Map = flagMap
+	}
+	if changed("lang") {
+		s.Lang = &flagLang
+	}
+	if changed("max-file-size") {
+		s.MaxFileSize = &flagMaxFileSize
+	}
+	if changed("keep") {
+		s.Keep = flagKeep
+	}
+	if changed("keep-file") {
+		s.KeepFile = flagKeepFile
+	}
+	if changed("keep-license") {
+		s.KeepLicense = &flagKeepLicense
+	}
+	if changed("strip-magic") {
+		s.StripMagic = &flagStripMagic
+	}
+	if changed("strip-markers") {
+		s.StripMarkers = &flagStripMarks
+	}
+	if changed("suppressions") {
+		s.Suppressions = &flagSuppress
+	}
+	if changed("only") {
+		s.Only = flagOnly
+	}
+	if changed("skip") {
+		s.Skip = flagSkip
+	}
+	if changed("blank-lines") {
+		s.BlankLines = &flagBlankLines
+	}
+	if changed("max-blank-lines") {
+		s.MaxBlankLines = &flagMaxBlank
+	}
+	if changed("preserve-lines") {
+		s.PreserveLines = &flagPreserveLn
+	}
+	if changed("preserve-columns") {
+		s.PreserveColumns = &flagPreserveCol
+	}
+	return s
+}
+
+type profile struct {
+	rules *keep.Rules
+	opts  remover.Options
+}
+
+type profiles struct {
+	mu      sync.Mutex
+	finder  *config.Finder
+	cli     config.Settings
+	byKey   map[string]*profile
+	checked map[*config.Config]bool
+}
+
+func newProfiles(finder *config.Finder, cli config.Settings) *profiles {
+	return &profiles{finder: finder, cli: cli, byKey: map[string]*profile{}, checked: map[*config.Config]bool{}}
+}
+
+func (p *profiles) check(stack config.Stack) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, err := p.build(stack.Base(p.cli)); err != nil {
+		return err
+	}
+	for _, c := range stack {
+		if p.checked[c] {
+			continue
+		}
+		p.checked[c] = true
+		sections := c.Sections()
+		names := make([]string, 0, len(sections))
+		for name := range sections {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			e := config.Default()
+			sections[name].Apply(&e)
+			p.cli.Apply(&e)
+			if _, err := p.build(e); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	}
 	return nil
 }
+
+func (p *profiles) get(path, lang string) (*profile, error) {
+	stack, err := p.finder.Stack(path)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.build(stack.Effective(path, lang, p.cli))
+}
+
+func (p *profiles) build(e config.Effective) (*profile, error) {
+	key := fmt.Sprint(e)
+	if prof, ok := p.byKey[key]; ok {
+		return prof, nil
+	}
+	rules, err := loadKeepRules(e)
+	if err != nil {
+		return nil, err
+	}
+	opts, err := loadRemoveOptions(e)
+	if err != nil {
+		return nil, err
+	}
+	prof := &profile{rules: rules, opts: opts}
+	p.byKey[key] = prof
+	return prof, nil
+}
+
+func loadKeepRules(e config.Effective) (*keep.Rules, error) {
+	patterns := append([]string(nil), e.Keep...)
+	for _, path := range e.KeepFile {
+		fromFile, err := keep.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("keep file: %w", err)
+		}
+		patterns = append(patterns, fromFile...)
+	}
+	rules, err := keep.Compile(patterns)
+	if err != nil {
+		return nil, err
+	}
+	rules.License = e.KeepLicense
+	rules.StripMagic = e.StripMagic
+	rules.StripMarkers = e.StripMarkers
+	rules.Suppressions, err = keep.ParseSuppressionMode(e.Suppressions)
+	if err != nil {
+		return nil, err
+	}
+	if rules.Only, err = parser.ParseKinds(e.Only); err != nil {
+		return nil, fmt.Errorf("only: %w", err)
+	}
+	if rules.Skip, err = parser.ParseKinds(e.Skip); err != nil {
+		return nil, fmt.Errorf("skip: %w", err)
+	}
+	return rules, nil
+}
+
+func loadRemoveOptions(e config.Effective) (remover.Options, error) {
+	blanks, err := remover.ParseBlankPolicy(e.BlankLines)
+	if err != nil {
+		return remover.Options{}, err
+	}
+	if e.MaxBlankLines < 0 {
+		return remover.Options{}, fmt.Errorf("max-blank-lines must not be negative, got %d", e.MaxBlankLines)
+	}
+	if (e.PreserveLines || e.PreserveColumns) && blanks != remover.BlanksPreserve {
+		return remover.Options{}, fmt.Errorf("blank-lines %s cannot be combined with preserve-lines or preserve-columns", e.BlankLines)
+	}
+	return remover.Options{
+		Blanks:          blanks,
+		MaxBlank:        e.MaxBlankLines,
+		PreserveLines:   e.PreserveLines,
+		PreserveColumns: e.PreserveColumns,
+	}, nil
+}
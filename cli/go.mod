@@ -3,14 +3,16 @@ module github.com/KashifKhn/remove-comments/cli
 go 1.24
 
 require (
+	github.com/BurntSushi/toml v1.6.0
 	github.com/boyter/gocodewalker v1.5.1
+	github.com/fatih/color v1.18.0
 	github.com/smacker/go-tree-sitter v0.0.0-20240827094217-dd81d9e9be82
 	github.com/spf13/cobra v1.10.2
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/danwakefield/fnmatch v0.0.0-20160403171240-cbb64ac3d964 // indirect
-	github.com/fatih/color v1.18.0 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
@@ -0,0 +1,394 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+
+	"github.com/KashifKhn/remove-comments/cli/internal/languages"
+)
+
+var extendsModeline = regexp.MustCompile(`^;+\s*extends\s*$`)
+
+var FileNames = []string{".remove-comments.yaml", ".remove-comments.yml", ".remove-comments.toml"}
+
+type Config struct {
+	Settings   `yaml:",inline"`
+	Root       bool                `yaml:"root" toml:"root"`
+	Extensions map[string]string   `yaml:"extensions" toml:"extensions"`
+	Queries    map[string]string   `yaml:"queries" toml:"queries"`
+	Languages  map[string]Settings `yaml:"languages" toml:"languages"`
+	Overrides  []Override          `yaml:"overrides" toml:"overrides"`
+	Path       string              `yaml:"-" toml:"-"`
+	dir        string
+}
+
+type Override struct {
+	Files    []string `yaml:"files" toml:"files"`
+	Settings `yaml:",inline"`
+}
+
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &Config{Path: path, dir: filepath.Dir(path)}
+	if filepath.Ext(path) == ".toml" {
+		md, err := toml.Decode(string(data), cfg)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if keys := md.Undecoded(); len(keys) > 0 {
+			return nil, fmt.Errorf("%s: unknown key %q", path, keys[0].String())
+		}
+	} else {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if err := cfg.check(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+func (c *Config) check() error {
+	c.Settings.KeepFile = c.resolve(c.Settings.KeepFile)
+	for name, s := range c.Languages {
+		if err := s.checkScoped(); err != nil {
+			return fmt.Errorf("languages.%s: %w", name, err)
+		}
+		s.KeepFile = c.resolve(s.KeepFile)
+		c.Languages[name] = s
+	}
+	for i := range c.Overrides {
+		o := &c.Overrides[i]
+		if len(o.Files) == 0 {
+			return fmt.Errorf("overrides[%d]: files is required", i)
+		}
+		if err := o.checkScoped(); err != nil {
+			return fmt.Errorf("overrides[%d]: %w", i, err)
+		}
+		o.KeepFile = c.resolve(o.KeepFile)
+	}
+	return nil
+}
+
+func (c *Config) resolve(paths []string) []string {
+	for i, p := range paths {
+		if !filepath.IsAbs(p) {
+			paths[i] = filepath.Join(c.dir, p)
+		}
+	}
+	return paths
+}
+
+func (c *Config) Sections() map[string]Settings {
+	sections := map[string]Settings{c.Path: c.Settings}
+	for name, s := range c.Languages {
+		sections[fmt.Sprintf("%s: languages.%s", c.Path, name)] = s
+	}
+	for i, o := range c.Overrides {
+		sections[fmt.Sprintf("%s: overrides[%d]", c.Path, i)] = o.Settings
+	}
+	return sections
+}
+
+func (c *Config) matches(o Override, path string) bool {
+	return c.matchesAny(o.Files, path)
+}
+
+func (c *Config) excludes(path string) bool {
+	return c.matchesAny(c.Exclude, path)
+}
+
+func (c *Config) matchesAny(patterns []string, path string) bool {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	dir, err := filepath.Abs(c.dir)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(dir, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return false
+	}
+	for _, pattern := range patterns {
+		if matchGlob(pattern, filepath.ToSlash(rel)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Config) Apply() error {
+	for _, ext := range sortedKeys(c.Extensions) {
+		name := c.Extensions[ext]
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		if err := languages.Alias(ext, name); err != nil {
+			return fmt.Errorf("config: %w", err)
+		}
+	}
+
+	queries := map[string]string{}
+	for name, query := range c.Queries {
+		queries[name] = query
+	}
+	files, err := filepath.Glob(filepath.Join(c.dir, "queries", "*.scm"))
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	for _, path := range files {
+		name := strings.TrimSuffix(filepath.Base(path), ".scm")
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("config: %w", err)
+		}
+		if !extends(data) {
+			queries[name] = string(data)
+			continue
+		}
+		base, ok := queries[name]
+		if !ok {
+			lang, found := languages.ByName(name)
+			if !found {
+				return fmt.Errorf("config: %s: unknown language %q", path, name)
+			}
+			base = lang.Query
+		}
+		queries[name] = base + "\n" + string(data)
+	}
+
+	for _, name := range sortedKeys(queries) {
+		if err := languages.SetQuery(name, queries[name]); err != nil {
+			return fmt.Errorf("config: %w", err)
+		}
+		lang, _ := languages.ByName(name)
+		if _, err := lang.CompileQuery(); err != nil {
+			return fmt.Errorf("config: %s query: %w", name, err)
+		}
+	}
+	return nil
+}
+
+type Stack []*Config
+
+type Finder struct {
+	Extra *Config
+	mu    sync.Mutex
+	byDir map[string]Stack
+}
+
+func Discover(target string) (Stack, error) {
+	return (&Finder{}).Stack(target)
+}
+
+func (f *Finder) Stack(target string) (Stack, error) {
+	dir, err := filepath.Abs(target)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(dir); err == nil && !info.IsDir() {
+		dir = filepath.Dir(dir)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.byDir == nil {
+		f.byDir = map[string]Stack{}
+	}
+	stack, err := f.dirStack(dir)
+	if err != nil || f.Extra == nil {
+		return stack, err
+	}
+	return append(append(Stack(nil), stack...), f.Extra), nil
+}
+
+func (f *Finder) dirStack(dir string) (Stack, error) {
+	if stack, ok := f.byDir[dir]; ok {
+		return stack, nil
+	}
+	path, err := find(dir)
+	if err != nil {
+		return nil, err
+	}
+	var own *Config
+	if path != "" {
+		if own, err = Load(path); err != nil {
+			return nil, err
+		}
+	}
+
+	var stack Stack
+	if parent := filepath.Dir(dir); parent != dir && (own == nil || !own.Root) {
+		if stack, err = f.dirStack(parent); err != nil {
+			return nil, err
+		}
+	}
+	if own != nil {
+		stack = append(append(Stack(nil), stack...), own)
+	}
+	f.byDir[dir] = stack
+	return stack, nil
+}
+
+func find(dir string) (string, error) {
+	var found []string
+	for _, name := range FileNames {
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			found = append(found, path)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return "", nil
+	case 1:
+		return found[0], nil
+	}
+	return "", fmt.Errorf("config: %s: found both %s and %s", dir, filepath.Base(found[0]), filepath.Base(found[1]))
+}
+
+func (s Stack) Apply() error {
+	for _, c := range s {
+		if err := c.Apply(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s Stack) Scope(outer Stack, path string, cli Settings) (int64, bool, error) {
+	for _, c := range s {
+		if contains(outer, c) {
+			continue
+		}
+		if key := c.walkKey(); key != "" {
+			return 0, false, fmt.Errorf("config: %s: %s can only be set in configs at or above the target, skipping %s", c.Path, key, c.dir)
+		}
+		if c.excludes(path) {
+			return 0, false, nil
+		}
+	}
+	return s.Base(cli).MaxFileSize, true, nil
+}
+
+func (c *Config) walkKey() string {
+	switch {
+	case c.Extensions != nil:
+		return "extensions"
+	case c.Queries != nil:
+		return "queries"
+	case c.Map != nil:
+		return "map"
+	case c.Lang != nil:
+		return "lang"
+	}
+	if files, _ := filepath.Glob(filepath.Join(c.dir, "queries", "*.scm")); len(files) > 0 {
+		return "queries/" + filepath.Base(files[0])
+	}
+	return ""
+}
+
+func contains(s Stack, c *Config) bool {
+	for _, o := range s {
+		if o == c {
+			return true
+		}
+	}
+	return false
+}
+
+func (s Stack) Base(cli Settings) Effective {
+	e := Default()
+	for _, c := range s {
+		c.Settings.Apply(&e)
+	}
+	cli.Apply(&e)
+	return e
+}
+
+func (s Stack) Effective(path, lang string, cli Settings) Effective {
+	e := Default()
+	for _, c := range s {
+		c.Settings.Apply(&e)
+		if ls, ok := c.Languages[lang]; ok {
+			ls.Apply(&e)
+		}
+		for _, o := range c.Overrides {
+			if c.matches(o, path) {
+				o.Settings.Apply(&e)
+			}
+		}
+	}
+	cli.Apply(&e)
+	return e
+}
+
+func (s Stack) Sources() []string {
+	paths := make([]string, 0, len(s))
+	for _, c := range s {
+		paths = append(paths, c.Path)
+	}
+	return paths
+}
+
+func extends(query []byte) bool {
+	for _, line := range bytes.Split(query, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		return extendsModeline.Match(line)
+	}
+	return false
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s Stack) Show(w io.Writer, path, lang, reason string) error {
+	if len(s) == 0 {
+		fmt.Fprintln(w, "# no config files found")
+	} else {
+		fmt.Fprintln(w, "# sources:")
+		for _, src := range s.Sources() {
+			fmt.Fprintf(w, "#   %s\n", src)
+		}
+	}
+	switch {
+	case lang != "" && reason != "":
+		fmt.Fprintf(w, "# language: %s (%s)\n", lang, reason)
+	case lang != "":
+		fmt.Fprintf(w, "# language: %s\n", lang)
+	}
+	data, err := yaml.Marshal(s.Effective(path, lang, Settings{}))
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
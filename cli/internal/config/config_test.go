@@ -0,0 +1,333 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/KashifKhn/remove-comments/cli/internal/languages"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func load(t *testing.T, dir, content string) *Config {
+	t.Helper()
+	path := filepath.Join(dir, "config.yaml")
+	writeFile(t, path, content)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func TestApply_ExtensionsAndQueries(t *testing.T) {
+	cfg := load(t, t.TempDir(), "extensions:\n  .jsm: javascript\n  hh: cpp\nqueries:\n  toml: |\n    ((comment) @comment (#match? @comment \"TODO\"))\n")
+	if err := cfg.Apply(); err != nil {
+		t.Fatal(err)
+	}
+	for ext, name := range map[string]string{".jsm": "javascript", ".hh": "cpp"} {
+		lang, ok := languages.Get(ext)
+		if !ok || lang.Name != name {
+			t.Errorf("%s: got %q (found=%v), want %q", ext, lang.Name, ok, name)
+		}
+	}
+	toml, _ := languages.Get(".toml")
+	if !strings.Contains(toml.Query, "TODO") {
+		t.Errorf("toml query not overridden: %q", toml.Query)
+	}
+}
+
+func TestApply_QueryFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "queries", "css.scm"), ";; extends\n((comment) @doc (#match? @doc \"^/\\\\*\\\\*\"))\n")
+	writeFile(t, filepath.Join(dir, "queries", "cue.scm"), "((comment) @comment (#match? @comment \"^// drop\"))\n")
+	before, _ := languages.ByName("css")
+	if err := load(t, dir, "{}\n").Apply(); err != nil {
+		t.Fatal(err)
+	}
+
+	css, _ := languages.ByName("css")
+	if !strings.HasPrefix(css.Query, before.Query) || !strings.Contains(css.Query, "@doc") {
+		t.Errorf("css query should extend the built-in one, got %q", css.Query)
+	}
+	cue, _ := languages.ByName("cue")
+	if !strings.HasPrefix(cue.Query, "((comment) @comment (#match?") {
+		t.Errorf("cue query should be replaced, got %q", cue.Query)
+	}
+}
+
+func TestApply_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		scm    string
+		want   string
+	}{
+		{"bad query", "queries:\n  lua: \"(bogus) @comment\"\n", "", "lua query"},
+		{"unknown extension target", "extensions:\n  .cob: cobol\n", "", `unknown language "cobol"`},
+		{"unknown query language", "queries:\n  cobol: \"(comment) @comment\"\n", "", `unknown language "cobol"`},
+		{"bad query file", "{}\n", "(comment @comment", "elm query"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if tt.scm != "" {
+				writeFile(t, filepath.Join(dir, "queries", "elm.scm"), tt.scm)
+			}
+			err := load(t, dir, tt.config).Apply()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got %v, want error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad_InvalidYAML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, path, "extensions: [\n")
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), path) {
+		t.Errorf("expected error naming %s, got %v", path, err)
+	}
+}
+
+func TestExtends(t *testing.T) {
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"; extends\n(comment) @comment", true},
+		{"\n;;  extends  \n(comment) @comment", true},
+		{"(comment) @comment\n; extends", false},
+		{"; inherits: c\n(comment) @comment", false},
+	}
+	for _, tt := range tests {
+		if got := extends([]byte(tt.query)); got != tt.want {
+			t.Errorf("extends(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestLoad_TOML(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, ".remove-comments.toml")
+	writeFile(t, path, "keep = [\"TODO\"]\nkeep-file = [\"keep.txt\"]\n\n[languages.go]\nskip = [\"doc\"]\n\n[[overrides]]\nfiles = [\"api/\"]\nkeep-license = true\n")
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Keep) != 1 || cfg.Keep[0] != "TODO" {
+		t.Errorf("keep = %v", cfg.Keep)
+	}
+	if want := filepath.Join(dir, "keep.txt"); len(cfg.KeepFile) != 1 || cfg.KeepFile[0] != want {
+		t.Errorf("keep-file = %v, want [%s]", cfg.KeepFile, want)
+	}
+	if skip := cfg.Languages["go"].Skip; len(skip) != 1 || skip[0] != "doc" {
+		t.Errorf("languages.go.skip = %v", skip)
+	}
+	if len(cfg.Overrides) != 1 || cfg.Overrides[0].KeepLicense == nil || !*cfg.Overrides[0].KeepLicense {
+		t.Errorf("overrides = %+v", cfg.Overrides)
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    string
+	}{
+		{"unknown yaml key", "c.yaml", "kep: [TODO]\n", "kep"},
+		{"unknown toml key", "c.toml", "kep = [\"TODO\"]\n", `unknown key "kep"`},
+		{"scoped exclude", "c.yaml", "languages:\n  go:\n    exclude: [x]\n", "languages.go: exclude can only be set at the top level"},
+		{"scoped map", "c.yaml", "languages:\n  sh:\n    map: [\"bin/*=bash\"]\n", "languages.sh: map can only be set at the top level"},
+		{"scoped max-file-size", "c.yaml", "overrides:\n  - files: [a]\n    max-file-size: 1\n", "overrides[0]: max-file-size"},
+		{"override without files", "c.yaml", "overrides:\n  - keep: [TODO]\n", "overrides[0]: files is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			writeFile(t, path, tt.content)
+			if _, err := Load(path); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got %v, want error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiscover_Hierarchy(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, ".remove-comments.yaml"), "keep: [OUTER]\n")
+	writeFile(t, filepath.Join(dir, "repo", ".remove-comments.yaml"), "root: true\nkeep: [TODO]\n")
+	writeFile(t, filepath.Join(dir, "repo", "sub", ".remove-comments.toml"), "keep = [\"FIXME\"]\n")
+	writeFile(t, filepath.Join(dir, "repo", "sub", "deep", "a.go"), "package a\n")
+
+	stack, err := Discover(filepath.Join(dir, "repo", "sub", "deep", "a.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(dir, "repo", ".remove-comments.yaml"), filepath.Join(dir, "repo", "sub", ".remove-comments.toml")}
+	if got := stack.Sources(); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("sources = %v, want %v", got, want)
+	}
+	if keep := stack.Base(Settings{}).Keep; strings.Join(keep, ",") != "TODO,FIXME" {
+		t.Errorf("keep = %v, want [TODO FIXME]", keep)
+	}
+}
+
+func TestDiscover_BothFormats(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, ".remove-comments.yaml"), "root: true\n")
+	writeFile(t, filepath.Join(dir, ".remove-comments.toml"), "root = true\n")
+	if _, err := Discover(dir); err == nil || !strings.Contains(err.Error(), "found both") {
+		t.Errorf("expected an error for two config files, got %v", err)
+	}
+}
+
+func TestStack_Scope(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, ".remove-comments.yaml"), "root: true\nexclude: [\"*.gen.go\"]\nmax-file-size: 100\n")
+	writeFile(t, filepath.Join(dir, "keep", ".remove-comments.yaml"), "keep: [TODO]\n")
+	writeFile(t, filepath.Join(dir, "svc", ".remove-comments.yaml"), "exclude: [\"*.pb.go\", \"gen/\"]\nmax-file-size: 10\n")
+	writeFile(t, filepath.Join(dir, "ext", ".remove-comments.yaml"), "extensions:\n  .jsm: python\n")
+	writeFile(t, filepath.Join(dir, "lang", ".remove-comments.toml"), "lang = \"go\"\n")
+	writeFile(t, filepath.Join(dir, "query", ".remove-comments.yaml"), "{}\n")
+	writeFile(t, filepath.Join(dir, "query", "queries", "go.scm"), "(comment) @comment\n")
+
+	finder := &Finder{}
+	outer, err := finder.Stack(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		file  string
+		limit int64
+		ok    bool
+		err   string
+	}{
+		{"a.pb.go", 100, true, ""},
+		{"keep/a.go", 100, true, ""},
+		{"svc/a.go", 10, true, ""},
+		{"svc/a.pb.go", 0, false, ""},
+		{"svc/gen/a.go", 0, false, ""},
+		{"ext/a.go", 0, false, "extensions can only be set"},
+		{"lang/a.go", 0, false, "lang can only be set"},
+		{"query/a.go", 0, false, "queries/go.scm can only be set"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			path := filepath.Join(dir, tt.file)
+			stack, err := finder.Stack(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			limit, ok, err := stack.Scope(outer, path, Settings{})
+			switch {
+			case tt.err == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Errorf("got %v, want error containing %q", err, tt.err)
+			}
+			if limit != tt.limit || ok != tt.ok {
+				t.Errorf("got (%d, %v), want (%d, %v)", limit, ok, tt.limit, tt.ok)
+			}
+			if _, ok, err := stack.Scope(stack, path, Settings{}); err != nil || !ok {
+				t.Errorf("a config at the target should not skip the file: %v", err)
+			}
+		})
+	}
+}
+
+func TestFinder_Extra(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, ".remove-comments.yaml"), "root: true\nblank-lines: collapse\n")
+	extra := load(t, t.TempDir(), "blank-lines: remove\n")
+	stack, err := (&Finder{Extra: extra}).Stack(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := stack.Base(Settings{}).BlankLines; got != "remove" {
+		t.Errorf("blank-lines = %q, want the --config value to win", got)
+	}
+}
+
+func TestStack_Effective(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, ".remove-comments.yaml"), `root: true
+keep: [TODO]
+blank-lines: collapse
+languages:
+  python:
+    skip: [doc]
+overrides:
+  - files: [api/]
+    skip: [doc, directive]
+    keep-license: true
+  - files: ["*_test.go"]
+    only: [line]
+`)
+	stack, err := Discover(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	remove := "remove"
+	tests := []struct {
+		path, lang string
+		cli        Settings
+		check      func(Effective) bool
+	}{
+		{"main.go", "go", Settings{}, func(e Effective) bool {
+			return e.Skip == nil && !e.KeepLicense && e.BlankLines == "collapse"
+		}},
+		{"x.py", "python", Settings{}, func(e Effective) bool {
+			return strings.Join(e.Skip, ",") == "doc"
+		}},
+		{"api/v1/h.py", "python", Settings{}, func(e Effective) bool {
+			return strings.Join(e.Skip, ",") == "doc,directive" && e.KeepLicense
+		}},
+		{"pkg/a_test.go", "go", Settings{}, func(e Effective) bool {
+			return strings.Join(e.Only, ",") == "line"
+		}},
+		{"main.go", "go", Settings{Keep: []string{"HACK"}, BlankLines: &remove}, func(e Effective) bool {
+			return strings.Join(e.Keep, ",") == "TODO,HACK" && e.BlankLines == "remove"
+		}},
+		{"../elsewhere/api/h.go", "go", Settings{}, func(e Effective) bool {
+			return !e.KeepLicense
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			e := stack.Effective(filepath.Join(dir, tt.path), tt.lang, tt.cli)
+			if !tt.check(e) {
+				t.Errorf("unexpected effective settings: %+v", e)
+			}
+		})
+	}
+}
+
+func TestStack_Show(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, ".remove-comments.yaml"), "root: true\nlanguages:\n  go:\n    keep: [NOLINT]\n")
+	stack, err := Discover(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if err := stack.Show(&b, filepath.Join(dir, "a.go"), "go", ""); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"# sources:\n#   " + filepath.Join(dir, ".remove-comments.yaml"), "# language: go", "keep:\n    - NOLINT", "blank-lines: preserve"} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, b.String())
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package config
+
+import "testing"
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern, rel string
+		want         bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "pkg/deep/main.go", true},
+		{"*.go", "main.py", false},
+		{"api/", "api/v1/h.go", true},
+		{"api/", "internal/api/h.go", false},
+		{"./api/**", "api/h.go", true},
+		{"api/*.go", "api/v1/h.go", false},
+		{"**/gen/*.go", "gen/a.go", true},
+		{"**/gen/*.go", "x/y/gen/a.go", true},
+		{"src/**/*_test.go", "src/a_test.go", true},
+		{"src/**/*_test.go", "src/a/b/c_test.go", true},
+		{"src/**/*_test.go", "lib/a_test.go", false},
+	}
+	for _, tt := range tests {
+		if got := matchGlob(tt.pattern, tt.rel); got != tt.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", tt.pattern, tt.rel, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package config
+
+import "fmt"
+
+type Settings struct {
+	Exclude         []string `yaml:"exclude" toml:"exclude"`
+	Map             []string `yaml:"map" toml:"map"`
+	Lang            *string  `yaml:"lang" toml:"lang"`
+	MaxFileSize     *int64   `yaml:"max-file-size" toml:"max-file-size"`
+	Keep            []string `yaml:"keep" toml:"keep"`
+	KeepFile        []string `yaml:"keep-file" toml:"keep-file"`
+	KeepLicense     *bool    `yaml:"keep-license" toml:"keep-license"`
+	StripMagic      *bool    `yaml:"strip-magic" toml:"strip-magic"`
+	StripMarkers    *bool    `yaml:"strip-markers" toml:"strip-markers"`
+	Suppressions    *string  `yaml:"suppressions" toml:"suppressions"`
+	Only            []string `yaml:"only" toml:"only"`
+	Skip            []string `yaml:"skip" toml:"skip"`
+	BlankLines      *string  `yaml:"blank-lines" toml:"blank-lines"`
+	MaxBlankLines   *int     `yaml:"max-blank-lines" toml:"max-blank-lines"`
+	PreserveLines   *bool    `yaml:"preserve-lines" toml:"preserve-lines"`
+	PreserveColumns *bool    `yaml:"preserve-columns" toml:"preserve-columns"`
+}
+
+type Effective struct {
+	Exclude         []string `yaml:"exclude"`
+	Map             []string `yaml:"map"`
+	Lang            string   `yaml:"lang"`
+	MaxFileSize     int64    `yaml:"max-file-size"`
+	Keep            []string `yaml:"keep"`
+	KeepFile        []string `yaml:"keep-file"`
+	KeepLicense     bool     `yaml:"keep-license"`
+	StripMagic      bool     `yaml:"strip-magic"`
+	StripMarkers    bool     `yaml:"strip-markers"`
+	Suppressions    string   `yaml:"suppressions"`
+	Only            []string `yaml:"only"`
+	Skip            []string `yaml:"skip"`
+	BlankLines      string   `yaml:"blank-lines"`
+	MaxBlankLines   int      `yaml:"max-blank-lines"`
+	PreserveLines   bool     `yaml:"preserve-lines"`
+	PreserveColumns bool     `yaml:"preserve-columns"`
+}
+
+func Default() Effective {
+	return Effective{
+		MaxFileSize:   10 * 1024 * 1024,
+		Suppressions:  "keep",
+		BlankLines:    "preserve",
+		MaxBlankLines: 1,
+	}
+}
+
+func (s Settings) Apply(e *Effective) {
+	e.Exclude = append(e.Exclude, s.Exclude...)
+	e.Map = append(e.Map, s.Map...)
+	e.Keep = append(e.Keep, s.Keep...)
+	e.KeepFile = append(e.KeepFile, s.KeepFile...)
+	if s.Only != nil {
+		e.Only = s.Only
+	}
+	if s.Skip != nil {
+		e.Skip = s.Skip
+	}
+	set(&e.Lang, s.Lang)
+	set(&e.MaxFileSize, s.MaxFileSize)
+	set(&e.KeepLicense, s.KeepLicense)
+	set(&e.StripMagic, s.StripMagic)
+	set(&e.StripMarkers, s.StripMarkers)
+	set(&e.Suppressions, s.Suppressions)
+	set(&e.BlankLines, s.BlankLines)
+	set(&e.MaxBlankLines, s.MaxBlankLines)
+	set(&e.PreserveLines, s.PreserveLines)
+	set(&e.PreserveColumns, s.PreserveColumns)
+}
+
+func set[T any](dst *T, v *T) {
+	if v != nil {
+		*dst = *v
+	}
+}
+
+func (s Settings) checkScoped() error {
+	switch {
+	case s.Exclude != nil:
+		return fmt.Errorf("exclude can only be set at the top level")
+	case s.Map != nil:
+		return fmt.Errorf("map can only be set at the top level")
+	case s.Lang != nil:
+		return fmt.Errorf("lang can only be set at the top level")
+	case s.MaxFileSize != nil:
+		return fmt.Errorf("max-file-size can only be set at the top level")
+	}
+	return nil
+}
@@ -3,13 +3,33 @@ package diff
 import (
 	"bytes"
 	"fmt"
+	"strings"
 )
 
+const maxEditDistance = 2048
+
 type Result struct {
-	Path    string
-	Before  []byte
-	After   []byte
-	Changed bool
+	Path         string
+	Before       []byte
+	After        []byte
+	Changed      bool
+	Kept         map[int]bool
+	BlankLines   int
+	ClearedLines int
+}
+
+type opKind int
+
+const (
+	opEqual opKind = iota
+	opDelete
+	opInsert
+)
+
+type op struct {
+	kind opKind
+	bi   int
+	ai   int
 }
 
 func Compute(path string, before, after []byte) Result {
@@ -21,10 +41,23 @@ func Compute(path string, before, after []byte) Result {
 	}
 }
 
+func (r *Result) MarkKept(startRow, endRow int) {
+	if r.Kept == nil {
+		r.Kept = map[int]bool{}
+	}
+	for row := startRow; row <= endRow; row++ {
+		r.Kept[row] = true
+	}
+}
+
 func (r Result) LinesRemoved() int {
 	return countLines(r.Before) - countLines(r.After)
 }
 
+func (r Result) CommentLinesRemoved() int {
+	return r.LinesRemoved() - r.BlankLines + r.ClearedLines
+}
+
 func (r Result) Unified() string {
 	if !r.Changed {
 		return ""
@@ -35,47 +68,170 @@ func (r Result) Unified() string {
 	var buf bytes.Buffer
 	fmt.Fprintf(&buf, "--- %s\n+++ %s\n", r.Path, r.Path)
 
-	bi, ai := 0, 0
-	for bi < len(before) || ai < len(after) {
-		if bi < len(before) && ai < len(after) && before[bi] == after[ai] {
-			bi++
-			ai++
-			continue
+	for _, o := range lineOps(before, after) {
+		switch o.kind {
+		case opEqual:
+			if r.Kept[o.bi] {
+				fmt.Fprintf(&buf, " %s\n", trimEnding(before[o.bi]))
+			}
+		case opDelete:
+			fmt.Fprintf(&buf, "-%s\n", trimEnding(before[o.bi]))
+		case opInsert:
+			fmt.Fprintf(&buf, "+%s\n", trimEnding(after[o.ai]))
 		}
-		if bi < len(before) {
-			fmt.Fprintf(&buf, "-%s\n", before[bi])
-			bi++
+	}
+	return buf.String()
+}
+
+func lineOps(before, after []string) []op {
+	prefix := 0
+	for prefix < len(before) && prefix < len(after) && before[prefix] == after[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(before)-prefix && suffix < len(after)-prefix &&
+		before[len(before)-1-suffix] == after[len(after)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]op, 0, len(before)+len(after))
+	for i := 0; i < prefix; i++ {
+		ops = append(ops, op{kind: opEqual, bi: i, ai: i})
+	}
+
+	b := before[prefix : len(before)-suffix]
+	a := after[prefix : len(after)-suffix]
+	middle, ok := myers(b, a)
+	if !ok {
+		middle = middle[:0]
+		for i := range b {
+			middle = append(middle, op{kind: opDelete, bi: i})
 		}
-		if ai < len(after) {
-			fmt.Fprintf(&buf, "+%s\n", after[ai])
-			ai++
+		for i := range a {
+			middle = append(middle, op{kind: opInsert, ai: i})
 		}
 	}
-	return buf.String()
+	for _, o := range middle {
+		o.bi += prefix
+		o.ai += prefix
+		ops = append(ops, o)
+	}
+
+	for i := 0; i < suffix; i++ {
+		ops = append(ops, op{kind: opEqual, bi: len(before) - suffix + i, ai: len(after) - suffix + i})
+	}
+	return ops
 }
 
-func countLines(b []byte) int {
-	if len(b) == 0 {
-		return 0
+func myers(a, b []string) ([]op, bool) {
+	n, m := len(a), len(b)
+	if n == 0 || m == 0 {
+		var ops []op
+		for i := 0; i < n; i++ {
+			ops = append(ops, op{kind: opDelete, bi: i})
+		}
+		for j := 0; j < m; j++ {
+			ops = append(ops, op{kind: opInsert, ai: j})
+		}
+		return ops, true
+	}
+
+	limit := n + m
+	if limit > maxEditDistance {
+		limit = maxEditDistance
+	}
+	offset := limit + 1
+	v := make([]int, 2*limit+3)
+	var trace [][]int
+
+	found := -1
+	for d := 0; d <= limit && found < 0; d++ {
+		snapshot := make([]int, 2*d+1)
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = d
+			}
+		}
+		for k := -d; k <= d; k++ {
+			snapshot[k+d] = v[offset+k]
+		}
+		trace = append(trace, snapshot)
+	}
+	if found < 0 {
+		return nil, false
+	}
+
+	var rev []op
+	x, y := n, m
+	for d := found; d > 0; d-- {
+		prev := trace[d-1]
+		at := func(k int) int { return prev[k+d-1] }
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			rev = append(rev, op{kind: opEqual, bi: x, ai: y})
+		}
+		if x == prevX {
+			y--
+			rev = append(rev, op{kind: opInsert, ai: y})
+		} else {
+			x--
+			rev = append(rev, op{kind: opDelete, bi: x})
+		}
 	}
-	n := bytes.Count(b, []byte("\n"))
-	if b[len(b)-1] != '\n' {
-		n++
+	for x > 0 && y > 0 {
+		x--
+		y--
+		rev = append(rev, op{kind: opEqual, bi: x, ai: y})
 	}
-	return n
+
+	ops := make([]op, len(rev))
+	for i := range rev {
+		ops[i] = rev[len(rev)-1-i]
+	}
+	return ops, true
+}
+
+func countLines(b []byte) int {
+	return len(splitLines(b))
 }
 
 func splitLines(b []byte) []string {
-	if len(b) == 0 {
-		return nil
-	}
-	raw := bytes.Split(b, []byte("\n"))
-	result := make([]string, 0, len(raw))
-	for _, l := range raw {
-		result = append(result, string(l))
+	var result []string
+	start := 0
+	for i, c := range b {
+		if c == '\n' || (c == '\r' && (i+1 == len(b) || b[i+1] != '\n')) {
+			result = append(result, string(b[start:i+1]))
+			start = i + 1
+		}
 	}
-	if len(result) > 0 && result[len(result)-1] == "" {
-		result = result[:len(result)-1]
+	if start < len(b) {
+		result = append(result, string(b[start:]))
 	}
 	return result
 }
+
+func trimEnding(line string) string {
+	return strings.TrimRight(line, "\r\n")
+}
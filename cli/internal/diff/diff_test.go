@@ -1,6 +1,7 @@
 package diff
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -56,6 +57,9 @@ func TestCountLines(t *testing.T) {
 		{[]byte("a\nb\n"), 2},
 		{[]byte("a\nb"), 2},
 		{[]byte("a"), 1},
+		{[]byte("a\r\nb\r\n"), 2},
+		{[]byte("a\rb\r"), 2},
+		{[]byte("a\r\nb\nc\rd"), 4},
 	}
 	for _, tt := range tests {
 		got := countLines(tt.input)
@@ -64,3 +68,101 @@ func TestCountLines(t *testing.T) {
 		}
 	}
 }
+
+func TestCompute_Unified_RemovedLineInMiddle(t *testing.T) {
+	before := []byte("a\n// comment\nb\nc\n")
+	after := []byte("a\nb\nc\n")
+	got := Compute("foo.go", before, after).Unified()
+	want := "--- foo.go\n+++ foo.go\n-// comment\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestCompute_Unified_ModifiedAndRemoved(t *testing.T) {
+	before := []byte("x := 1 // inline\n// full\ny := 2\n")
+	after := []byte("x := 1\ny := 2\n")
+	got := Compute("foo.go", before, after).Unified()
+	want := "--- foo.go\n+++ foo.go\n-x := 1 // inline\n-// full\n+x := 1\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestCompute_Unified_KeptLinesShownAsContext(t *testing.T) {
+	before := []byte("// Copyright 2024 Acme\n// SPDX-License-Identifier: MIT\n\n// drop\nx := 1\n")
+	after := []byte("// Copyright 2024 Acme\n// SPDX-License-Identifier: MIT\n\nx := 1\n")
+	r := Compute("foo.go", before, after)
+	r.MarkKept(0, 1)
+	got := r.Unified()
+	want := "--- foo.go\n+++ foo.go\n // Copyright 2024 Acme\n // SPDX-License-Identifier: MIT\n-// drop\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestLineOps_ReconstructsBothSides(t *testing.T) {
+	tests := []struct {
+		before, after []string
+	}{
+		{[]string{"a", "b", "c"}, []string{"a", "c"}},
+		{[]string{"a", "b", "c", "d"}, []string{"b", "x", "d"}},
+		{[]string{}, []string{"a"}},
+		{[]string{"a"}, []string{}},
+		{[]string{"a", "a", "b", "a"}, []string{"a", "b", "a", "a"}},
+	}
+	for _, tt := range tests {
+		var gotBefore, gotAfter []string
+		for _, o := range lineOps(tt.before, tt.after) {
+			switch o.kind {
+			case opEqual:
+				gotBefore = append(gotBefore, tt.before[o.bi])
+				gotAfter = append(gotAfter, tt.after[o.ai])
+			case opDelete:
+				gotBefore = append(gotBefore, tt.before[o.bi])
+			case opInsert:
+				gotAfter = append(gotAfter, tt.after[o.ai])
+			}
+		}
+		if strings.Join(gotBefore, "\n") != strings.Join(tt.before, "\n") {
+			t.Errorf("before mismatch: got %v, want %v", gotBefore, tt.before)
+		}
+		if strings.Join(gotAfter, "\n") != strings.Join(tt.after, "\n") {
+			t.Errorf("after mismatch: got %v, want %v", gotAfter, tt.after)
+		}
+	}
+}
+
+func TestResult_CommentLinesRemoved(t *testing.T) {
+	r := Compute("foo.go", []byte("// a\n\n\nx\n"), []byte("\nx\n"))
+	r.BlankLines = 1
+	if r.LinesRemoved() != 2 {
+		t.Errorf("LinesRemoved = %d, want 2", r.LinesRemoved())
+	}
+	if r.CommentLinesRemoved() != 1 {
+		t.Errorf("CommentLinesRemoved = %d, want 1", r.CommentLinesRemoved())
+	}
+}
+
+func TestResult_CommentLinesRemoved_Cleared(t *testing.T) {
+	r := Compute("foo.go", []byte("// a\nx\n"), []byte("\nx\n"))
+	r.ClearedLines = 1
+	if r.LinesRemoved() != 0 || r.CommentLinesRemoved() != 1 {
+		t.Errorf("LinesRemoved = %d, CommentLinesRemoved = %d, want 0 and 1", r.LinesRemoved(), r.CommentLinesRemoved())
+	}
+}
+
+func TestCompute_Unified_LineEndings(t *testing.T) {
+	for name, eol := range map[string]string{"lf": "\n", "crlf": "\r\n", "cr": "\r"} {
+		before := []byte("a" + eol + "// comment" + eol + "b" + eol)
+		after := []byte("a" + eol + "b" + eol)
+		r := Compute("foo.go", before, after)
+		want := "--- foo.go\n+++ foo.go\n-// comment\n"
+		if got := r.Unified(); got != want {
+			t.Errorf("%s: got %q, want %q", name, got, want)
+		}
+		if r.LinesRemoved() != 1 {
+			t.Errorf("%s: LinesRemoved = %d, want 1", name, r.LinesRemoved())
+		}
+	}
+}
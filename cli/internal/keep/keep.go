@@ -0,0 +1,137 @@
+package keep
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/KashifKhn/remove-comments/cli/internal/parser"
+)
+
+type SuppressionMode int
+
+const (
+	SuppressionsKeep SuppressionMode = iota
+	SuppressionsRemove
+	SuppressionsList
+)
+
+func ParseSuppressionMode(s string) (SuppressionMode, error) {
+	switch s {
+	case "", "keep":
+		return SuppressionsKeep, nil
+	case "remove":
+		return SuppressionsRemove, nil
+	case "list":
+		return SuppressionsList, nil
+	}
+	return SuppressionsKeep, fmt.Errorf("invalid suppressions mode %q (want keep, remove or list)", s)
+}
+
+type Rules struct {
+	patterns     []*regexp.Regexp
+	License      bool
+	StripMagic   bool
+	StripMarkers bool
+	Suppressions SuppressionMode
+	Only         map[parser.Kind]bool
+	Skip         map[parser.Kind]bool
+}
+
+func Compile(patterns []string) (*Rules, error) {
+	r := &Rules{}
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("keep pattern %q: %w", p, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return r, nil
+}
+
+func ReadFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var patterns []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), " \t\r")
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return patterns, nil
+}
+
+func (r *Rules) Match(text []byte) bool {
+	if r == nil {
+		return false
+	}
+	for _, re := range r.patterns {
+		if re.Match(text) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rules) Split(src []byte, ranges []parser.CommentRange) (remove, kept []parser.CommentRange) {
+	m := findMarks(src, ranges)
+	if m.file {
+		return nil, ranges
+	}
+	for i, cr := range ranges {
+		switch {
+		case r.protects(cr):
+			kept = append(kept, cr)
+		case m.bare[i]:
+			if r != nil && r.StripMarkers {
+				remove = append(remove, cr)
+			} else {
+				kept = append(kept, cr)
+			}
+		case m.held[i] || r.keepsKind(cr) || r.Match(cr.Text(src)):
+			kept = append(kept, cr)
+		default:
+			remove = append(remove, cr)
+		}
+	}
+	return remove, kept
+}
+
+func (r *Rules) keepsKind(cr parser.CommentRange) bool {
+	if r == nil {
+		return false
+	}
+	license := cr.Kind == parser.KindLicense
+	if r.License && license {
+		return true
+	}
+	if r.Skip[cr.Kind] || (license && r.Skip[cr.Syntax]) {
+		return true
+	}
+	return len(r.Only) > 0 && !r.Only[cr.Kind] && !(license && r.Only[cr.Syntax])
+}
+
+func (r *Rules) protects(cr parser.CommentRange) bool {
+	switch cr.Protection {
+	case parser.ProtectMagic:
+		return r == nil || !r.StripMagic
+	case parser.ProtectDirective:
+		return true
+	case parser.ProtectSuppression:
+		return r == nil || r.Suppressions != SuppressionsRemove
+	}
+	return false
+}
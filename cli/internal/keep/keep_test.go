@@ -0,0 +1,306 @@
+package keep
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/KashifKhn/remove-comments/cli/internal/languages"
+	"github.com/KashifKhn/remove-comments/cli/internal/parser"
+)
+
+func rangeOf(src, text string) parser.CommentRange {
+	for i := 0; i+len(text) <= len(src); i++ {
+		if src[i:i+len(text)] == text {
+			return parser.CommentRange{StartByte: uint32(i), EndByte: uint32(i + len(text))}
+		}
+	}
+	panic("text not found: " + text)
+}
+
+func TestCompile_InvalidPattern(t *testing.T) {
+	if _, err := Compile([]string{"TODO", "("}); err == nil {
+		t.Error("expected error for invalid regex")
+	}
+}
+
+func TestRules_Match(t *testing.T) {
+	r, err := Compile([]string{`TODO`, `^// SAFETY:`, `NOTE\(team\)`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"// TODO: fix", true},
+		{"# TODO later", true},
+		{"// SAFETY: pointer is valid", true},
+		{"x // SAFETY: not anchored", false},
+		{"/* NOTE(team) keep */", true},
+		{"// NOTE: plain", false},
+		{"// regular comment", false},
+	}
+	for _, tt := range tests {
+		if got := r.Match([]byte(tt.text)); got != tt.want {
+			t.Errorf("Match(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestRules_NilMatchesNothing(t *testing.T) {
+	var r *Rules
+	if r.Match([]byte("// TODO")) {
+		t.Error("nil rules should not match")
+	}
+}
+
+func TestRules_Split(t *testing.T) {
+	src := "x := 1 // TODO: remove\n// plain\n// FIXME later\n"
+	ranges := []parser.CommentRange{
+		rangeOf(src, "// TODO: remove"),
+		rangeOf(src, "// plain"),
+		rangeOf(src, "// FIXME later"),
+	}
+	r, err := Compile([]string{"TODO", "FIXME"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	remove, kept := r.Split([]byte(src), ranges)
+	if len(remove) != 1 || string(remove[0].Text([]byte(src))) != "// plain" {
+		t.Errorf("unexpected remove set: %v", remove)
+	}
+	if len(kept) != 2 {
+		t.Errorf("expected 2 kept, got %d", len(kept))
+	}
+}
+
+func TestRules_Split_EmptyKeepsNothing(t *testing.T) {
+	src := "// a\n// b\n"
+	ranges := []parser.CommentRange{rangeOf(src, "// a"), rangeOf(src, "// b")}
+	r, err := Compile(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	remove, kept := r.Split([]byte(src), ranges)
+	if len(remove) != 2 || len(kept) != 0 {
+		t.Errorf("got remove=%d kept=%d, want 2/0", len(remove), len(kept))
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keep.txt")
+	content := "# comment line\nTODO\n\n\\#\\s*noqa\r\nFIXME  \n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"TODO", `\#\s*noqa`, "FIXME"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("pattern %d: got %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestReadFile_Missing(t *testing.T) {
+	if _, err := ReadFile(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func rangesOf(src string, texts ...string) []parser.CommentRange {
+	ranges := make([]parser.CommentRange, 0, len(texts))
+	from := 0
+	for _, text := range texts {
+		cr := rangeOf(src[from:], text)
+		cr.StartByte += uint32(from)
+		cr.EndByte += uint32(from)
+		from = int(cr.EndByte)
+		ranges = append(ranges, cr)
+	}
+	return ranges
+}
+
+func keptTexts(src string, kept []parser.CommentRange) []string {
+	var out []string
+	for _, cr := range kept {
+		out = append(out, string(cr.Text([]byte(src))))
+	}
+	return out
+}
+
+func TestSplit_License(t *testing.T) {
+	src := "// Copyright 2024 Acme\n\n// Package x does y.\n"
+	ranges := rangesOf(src, "// Copyright 2024 Acme", "// Package x does y.")
+	ranges[0].Kind = parser.KindLicense
+	ranges[1].Kind = parser.KindDoc
+
+	remove, kept := (&Rules{}).Split([]byte(src), ranges)
+	if len(kept) != 0 || len(remove) != 2 {
+		t.Errorf("license off: got remove=%d kept=%d, want 2/0", len(remove), len(kept))
+	}
+
+	_, kept = (&Rules{License: true}).Split([]byte(src), ranges)
+	got := keptTexts(src, kept)
+	if len(got) != 1 || got[0] != "// Copyright 2024 Acme" {
+		t.Errorf("license on: got %q", got)
+	}
+}
+
+func TestSplit_LicenseMatchesSyntaxKind(t *testing.T) {
+	src := "// Copyright 2024 Acme\n/* Licensed under MIT */\n"
+	ranges := rangesOf(src, "// Copyright 2024 Acme", "/* Licensed under MIT */")
+	ranges[0].Kind, ranges[0].Syntax = parser.KindLicense, parser.KindLine
+	ranges[1].Kind, ranges[1].Syntax = parser.KindLicense, parser.KindBlock
+
+	tests := []struct {
+		name string
+		r    *Rules
+		want []string
+	}{
+		{"only line", &Rules{Only: map[parser.Kind]bool{parser.KindLine: true}}, []string{"/* Licensed under MIT */"}},
+		{"only license", &Rules{Only: map[parser.Kind]bool{parser.KindLicense: true}}, nil},
+		{"skip block", &Rules{Skip: map[parser.Kind]bool{parser.KindBlock: true}}, []string{"/* Licensed under MIT */"}},
+		{"skip license", &Rules{Skip: map[parser.Kind]bool{parser.KindLicense: true}}, []string{"// Copyright 2024 Acme", "/* Licensed under MIT */"}},
+		{"only line with keep-license", &Rules{License: true, Only: map[parser.Kind]bool{parser.KindLine: true}}, []string{"// Copyright 2024 Acme", "/* Licensed under MIT */"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, kept := tt.r.Split([]byte(src), ranges)
+			got := keptTexts(src, kept)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplit_KindFilters(t *testing.T) {
+	src := "// line\n/* block */\n/** doc */\n"
+	ranges := rangesOf(src, "// line", "/* block */", "/** doc */")
+	ranges[1].Kind = parser.KindBlock
+	ranges[2].Kind = parser.KindDoc
+
+	tests := []struct {
+		name string
+		r    *Rules
+		want []string
+	}{
+		{"skip doc", &Rules{Skip: map[parser.Kind]bool{parser.KindDoc: true}}, []string{"/** doc */"}},
+		{"only line", &Rules{Only: map[parser.Kind]bool{parser.KindLine: true}}, []string{"/* block */", "/** doc */"}},
+		{"only line and block skip block", &Rules{
+			Only: map[parser.Kind]bool{parser.KindLine: true, parser.KindBlock: true},
+			Skip: map[parser.Kind]bool{parser.KindBlock: true},
+		}, []string{"/* block */", "/** doc */"}},
+		{"no filters", &Rules{}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, kept := tt.r.Split([]byte(src), ranges)
+			got := keptTexts(src, kept)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplit_ElixirDocAttributes(t *testing.T) {
+	src := "defmodule A do\n  @moduledoc false\n  # c\n  @doc \"f\"\n  def f, do: 1\nend\n"
+	cfg, _ := languages.Get(".ex")
+	ranges, err := parser.Parse([]byte(src), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		rules *Rules
+		want  string
+	}{
+		{"default", nil, "# c|@doc \"f\""},
+		{"only doc", &Rules{Only: map[parser.Kind]bool{parser.KindDoc: true}}, "@doc \"f\""},
+		{"skip doc", &Rules{Skip: map[parser.Kind]bool{parser.KindDoc: true}}, "# c"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remove, kept := tt.rules.Split([]byte(src), ranges)
+			if got := strings.Join(keptTexts(src, remove), "|"); got != tt.want {
+				t.Errorf("removed %q, want %q", got, tt.want)
+			}
+			for _, r := range kept {
+				if r.Protection != parser.Unprotected {
+					t.Errorf("%q is protected", r.Text([]byte(src)))
+				}
+			}
+		})
+	}
+}
+
+func TestSplit_MagicProtection(t *testing.T) {
+	src := "#!/bin/sh\n# plain\n"
+	ranges := rangesOf(src, "#!/bin/sh", "# plain")
+	ranges[0].Protection = parser.ProtectMagic
+
+	var none *Rules
+	remove, kept := none.Split([]byte(src), ranges)
+	if len(kept) != 1 || len(remove) != 1 {
+		t.Fatalf("nil rules: got remove=%d kept=%d, want 1/1", len(remove), len(kept))
+	}
+
+	strip := &Rules{StripMagic: true}
+	remove, kept = strip.Split([]byte(src), ranges)
+	if len(kept) != 0 || len(remove) != 2 {
+		t.Errorf("strip magic: got remove=%d kept=%d, want 2/0", len(remove), len(kept))
+	}
+}
+
+func TestParseSuppressionMode(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    SuppressionMode
+		wantErr bool
+	}{
+		{"", SuppressionsKeep, false},
+		{"keep", SuppressionsKeep, false},
+		{"remove", SuppressionsRemove, false},
+		{"list", SuppressionsList, false},
+		{"drop", SuppressionsKeep, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseSuppressionMode(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseSuppressionMode(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("ParseSuppressionMode(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSplit_SuppressionModes(t *testing.T) {
+	src := "x = 1  # noqa: E501\n# plain\n"
+	ranges := rangesOf(src, "# noqa: E501", "# plain")
+	ranges[0].Protection = parser.ProtectSuppression
+
+	for _, mode := range []SuppressionMode{SuppressionsKeep, SuppressionsList} {
+		r := &Rules{Suppressions: mode}
+		_, kept := r.Split([]byte(src), ranges)
+		if len(kept) != 1 {
+			t.Errorf("mode %v: expected suppression kept, got %d kept", mode, len(kept))
+		}
+	}
+
+	r := &Rules{Suppressions: SuppressionsRemove}
+	remove, kept := r.Split([]byte(src), ranges)
+	if len(remove) != 2 || len(kept) != 0 {
+		t.Errorf("remove mode: got remove=%d kept=%d, want 2/0", len(remove), len(kept))
+	}
+}
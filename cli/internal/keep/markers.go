@@ -0,0 +1,99 @@
+package keep
+
+import (
+	"bytes"
+	"regexp"
+	"unicode"
+
+	"github.com/KashifKhn/remove-comments/cli/internal/parser"
+)
+
+var marker = regexp.MustCompile(`\brmc:(keep|off|on|ignore-file)\b`)
+
+type marks struct {
+	held []bool
+	bare []bool
+	file bool
+}
+
+func findMarks(src []byte, ranges []parser.CommentRange) marks {
+	m := marks{held: make([]bool, len(ranges)), bare: make([]bool, len(ranges))}
+	found := make([][][]byte, len(ranges))
+	for i, cr := range ranges {
+		text := cr.Text(src)
+		for _, match := range marker.FindAllSubmatch(text, -1) {
+			found[i] = append(found[i], match[1])
+			m.file = m.file || string(match[1]) == "ignore-file"
+		}
+		if len(found[i]) == 0 {
+			continue
+		}
+		rest := marker.ReplaceAll(text, nil)
+		m.bare[i] = bytes.IndexFunc(rest, isWordRune) < 0
+	}
+	if m.file {
+		return m
+	}
+
+	off := false
+	for i := range ranges {
+		if off {
+			m.held[i] = true
+		}
+		for _, name := range found[i] {
+			switch string(name) {
+			case "off":
+				off = true
+			case "on":
+				off = false
+			case "keep":
+				m.keepAround(src, ranges, i)
+			}
+		}
+		if len(found[i]) > 0 && !m.bare[i] {
+			m.held[i] = true
+		}
+	}
+	return m
+}
+
+func (m marks) keepAround(src []byte, ranges []parser.CommentRange, i int) {
+	at := ranges[i]
+	for j := range ranges {
+		if j != i && ranges[j].StartRow <= at.EndRow && ranges[j].EndRow >= at.StartRow {
+			m.held[j] = true
+		}
+	}
+	if !aloneOnLine(src, at) {
+		return
+	}
+	prev := at
+	for j := i + 1; j < len(ranges); j++ {
+		next := ranges[j]
+		if next.StartRow <= at.EndRow {
+			continue
+		}
+		if next.StartRow != prev.EndRow+1 || marker.Match(next.Text(src)) {
+			return
+		}
+		m.held[j] = true
+		if !aloneOnLine(src, next) {
+			return
+		}
+		prev = next
+	}
+}
+
+func aloneOnLine(src []byte, cr parser.CommentRange) bool {
+	start := bytes.LastIndexByte(src[:cr.StartByte], '\n') + 1
+	end := bytes.IndexByte(src[cr.EndByte:], '\n')
+	if end < 0 {
+		end = len(src) - int(cr.EndByte)
+	}
+	return len(bytes.TrimSpace(src[start:cr.StartByte])) == 0 &&
+		len(bytes.TrimSpace(src[cr.EndByte:int(cr.EndByte)+end])) == 0
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
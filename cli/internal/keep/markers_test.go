@@ -0,0 +1,108 @@
+package keep
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/KashifKhn/remove-comments/cli/internal/languages"
+	"github.com/KashifKhn/remove-comments/cli/internal/parser"
+)
+
+func parseGo(t *testing.T, src string) []parser.CommentRange {
+	t.Helper()
+	cfg, _ := languages.Get(".go")
+	ranges, err := parser.Parse([]byte(src), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ranges
+}
+
+func TestSplit_Markers(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   string
+		strip bool
+		want  []string
+	}{
+		{
+			"keep above a block",
+			"package a\n\n// rmc:keep\n// one\n// two\n\n// three\nvar x = 1\n",
+			false,
+			[]string{"// rmc:keep", "// one", "// two"},
+		},
+		{
+			"keep above a trailing comment",
+			"package a\n\n// rmc:keep\nvar x = 1 // one\n// two\n",
+			false,
+			[]string{"// rmc:keep", "// one"},
+		},
+		{
+			"keep on the same line",
+			"package a\n\nvar x = 1 /* one */ // rmc:keep\n// two\n",
+			false,
+			[]string{"/* one */", "// rmc:keep"},
+		},
+		{
+			"keep inside the comment",
+			"package a\n\n// hot loop, see #12 rmc:keep\n// two\n\n// three\n",
+			true,
+			[]string{"// hot loop, see #12 rmc:keep", "// two"},
+		},
+		{
+			"off and on",
+			"package a\n\n// one\n// rmc:off\n// two\nvar x = 1 // three\n// rmc:on\n// four\n",
+			false,
+			[]string{"// rmc:off", "// two", "// three", "// rmc:on"},
+		},
+		{
+			"off without on",
+			"package a\n\n/* rmc:off */\n// one\n",
+			false,
+			[]string{"/* rmc:off */", "// one"},
+		},
+		{
+			"strip bare markers",
+			"package a\n\n// rmc:keep\n// one\n// rmc:off\n// two\n// rmc:on\n// three\n",
+			true,
+			[]string{"// one", "// two"},
+		},
+		{
+			"ignore file",
+			"package a\n\n// rmc:ignore-file\n// one\nvar x = 1 // two\n",
+			true,
+			[]string{"// rmc:ignore-file", "// one", "// two"},
+		},
+		{
+			"not a marker",
+			"package a\n\n// rmc:keeper\n// one\n",
+			false,
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Rules{StripMarkers: tt.strip}
+			remove, kept := r.Split([]byte(tt.src), parseGo(t, tt.src))
+			got := keptTexts(tt.src, kept)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("kept %q, want %q", got, tt.want)
+			}
+			if len(remove)+len(kept) != len(parseGo(t, tt.src)) {
+				t.Errorf("split lost comments: remove=%d kept=%d", len(remove), len(kept))
+			}
+		})
+	}
+}
+
+func TestSplit_MarkersKeepProtection(t *testing.T) {
+	src := "//go:build linux\n\n// rmc:off\n\npackage a\n"
+	ranges := parseGo(t, src)
+	remove, kept := (&Rules{StripMarkers: true}).Split([]byte(src), ranges)
+	if got := keptTexts(src, kept); len(got) != 1 || got[0] != "//go:build linux" {
+		t.Errorf("kept %q, want the build constraint only", got)
+	}
+	if len(remove) != 1 {
+		t.Errorf("expected the bare marker to be removed, got %d", len(remove))
+	}
+}
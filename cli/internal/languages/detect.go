@@ -0,0 +1,133 @@
+package languages
+
+import (
+	"bytes"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const modelineLines = 5
+
+var (
+	versionSuffix = regexp.MustCompile(`[\d.]+$`)
+	vimFiletype   = regexp.MustCompile(`(?:^|\s)(?:vi|vim|ex)(?:[<=>]?\d+)?:.*?\b(?:ft|filetype)=([\w+-]+)`)
+	emacsMode     = regexp.MustCompile(`-\*-\s*(?:.*;\s*)?mode:\s*([\w+-]+)`)
+	emacsBareMode = regexp.MustCompile(`-\*-\s*([\w+-]+)\s*-\*-`)
+)
+
+var interpreters = map[string]string{
+	"sh":         "bash",
+	"bash":       "bash",
+	"dash":       "bash",
+	"ash":        "bash",
+	"ksh":        "bash",
+	"mksh":       "bash",
+	"zsh":        "bash",
+	"python":     "python",
+	"pypy":       "python",
+	"node":       "javascript",
+	"nodejs":     "javascript",
+	"deno":       "typescript",
+	"bun":        "typescript",
+	"ts-node":    "typescript",
+	"tsx":        "typescript",
+	"ruby":       "ruby",
+	"jruby":      "ruby",
+	"php":        "php",
+	"lua":        "lua",
+	"luajit":     "lua",
+	"elixir":     "elixir",
+	"groovy":     "groovy",
+	"swift":      "swift",
+	"scala":      "scala",
+	"ocaml":      "ocaml",
+	"dart":       "dart",
+	"runhaskell": "haskell",
+	"runghc":     "haskell",
+}
+
+var modeAliases = map[string]string{
+	"sh":           "bash",
+	"zsh":          "bash",
+	"ksh":          "bash",
+	"shell-script": "bash",
+	"py":           "python",
+	"js":           "javascript",
+	"ts":           "typescript",
+	"rb":           "ruby",
+	"c++":          "cpp",
+	"cs":           "csharp",
+	"kt":           "kotlin",
+	"tf":           "hcl",
+	"terraform":    "hcl",
+	"proto":        "protobuf",
+	"yml":          "yaml",
+	"ex":           "elixir",
+	"hs":           "haskell",
+}
+
+func Detect(src []byte) (LangConfig, string, bool) {
+	if cfg, ok := fromShebang(src); ok {
+		return cfg, "shebang", true
+	}
+	if cfg, ok := fromModeline(src); ok {
+		return cfg, "modeline", true
+	}
+	return LangConfig{}, "", false
+}
+
+func fromShebang(src []byte) (LangConfig, bool) {
+	src = bytes.TrimPrefix(src, []byte("\xef\xbb\xbf"))
+	if !bytes.HasPrefix(src, []byte("#!")) {
+		return LangConfig{}, false
+	}
+	line, _, _ := bytes.Cut(src[2:], []byte("\n"))
+	fields := strings.Fields(string(line))
+	if len(fields) == 0 {
+		return LangConfig{}, false
+	}
+	interp := filepath.Base(fields[0])
+	if interp == "env" {
+		interp = ""
+		for _, f := range fields[1:] {
+			if !strings.HasPrefix(f, "-") && !strings.Contains(f, "=") {
+				interp = filepath.Base(f)
+				break
+			}
+		}
+	}
+	name, ok := interpreters[interp]
+	if !ok {
+		name, ok = interpreters[versionSuffix.ReplaceAllString(interp, "")]
+	}
+	if !ok {
+		return LangConfig{}, false
+	}
+	return ByName(name)
+}
+
+func fromModeline(src []byte) (LangConfig, bool) {
+	lines := bytes.Split(bytes.TrimRight(src, "\n"), []byte("\n"))
+	for i, line := range lines {
+		if i >= modelineLines && i < len(lines)-modelineLines {
+			continue
+		}
+		for _, re := range []*regexp.Regexp{vimFiletype, emacsMode, emacsBareMode} {
+			if m := re.FindSubmatch(line); m != nil {
+				if cfg, ok := byMode(string(m[1])); ok {
+					return cfg, true
+				}
+			}
+		}
+	}
+	return LangConfig{}, false
+}
+
+func byMode(mode string) (LangConfig, bool) {
+	mode = strings.TrimSuffix(strings.ToLower(mode), "-mode")
+	if name, ok := modeAliases[mode]; ok {
+		mode = name
+	}
+	return ByName(mode)
+}
@@ -1,19 +1,42 @@
 package languages
 
 import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+
 	"github.com/KashifKhn/remove-comments/cli/internal/dart"
+	"github.com/KashifKhn/remove-comments/cli/internal/haskell"
 	sitter "github.com/smacker/go-tree-sitter"
 	"github.com/smacker/go-tree-sitter/bash"
 	"github.com/smacker/go-tree-sitter/c"
 	"github.com/smacker/go-tree-sitter/cpp"
+	"github.com/smacker/go-tree-sitter/csharp"
 	"github.com/smacker/go-tree-sitter/css"
+	"github.com/smacker/go-tree-sitter/cue"
+	"github.com/smacker/go-tree-sitter/dockerfile"
+	"github.com/smacker/go-tree-sitter/elixir"
+	"github.com/smacker/go-tree-sitter/elm"
 	"github.com/smacker/go-tree-sitter/golang"
+	"github.com/smacker/go-tree-sitter/groovy"
+	"github.com/smacker/go-tree-sitter/hcl"
 	"github.com/smacker/go-tree-sitter/html"
 	"github.com/smacker/go-tree-sitter/java"
 	"github.com/smacker/go-tree-sitter/javascript"
+	"github.com/smacker/go-tree-sitter/kotlin"
 	"github.com/smacker/go-tree-sitter/lua"
+	"github.com/smacker/go-tree-sitter/ocaml"
+	"github.com/smacker/go-tree-sitter/php"
+	"github.com/smacker/go-tree-sitter/protobuf"
 	"github.com/smacker/go-tree-sitter/python"
+	"github.com/smacker/go-tree-sitter/ruby"
 	"github.com/smacker/go-tree-sitter/rust"
+	"github.com/smacker/go-tree-sitter/scala"
+	"github.com/smacker/go-tree-sitter/sql"
+	"github.com/smacker/go-tree-sitter/svelte"
+	"github.com/smacker/go-tree-sitter/swift"
 	"github.com/smacker/go-tree-sitter/toml"
 	"github.com/smacker/go-tree-sitter/typescript/tsx"
 	"github.com/smacker/go-tree-sitter/typescript/typescript"
@@ -21,153 +44,648 @@ import (
 )
 
 type LangConfig struct {
-	Name     string
-	Query    string
-	Language func() *sitter.Language
+	Name         string
+	Query        string
+	Language     func() *sitter.Language
+	Suppressions []*regexp.Regexp
+	Markup       bool
+}
+
+func (c LangConfig) IsSuppression(text []byte) bool {
+	for _, re := range c.Suppressions {
+		if re.Match(text) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c LangConfig) CompileQuery() (*sitter.Query, error) {
+	queryMu.Lock()
+	defer queryMu.Unlock()
+	key := c.Name + "\x00" + c.Query
+	if cq, ok := compiled[key]; ok {
+		return cq.query, cq.err
+	}
+	q, err := sitter.NewQuery([]byte(c.Query), c.Language())
+	compiled[key] = compiledQuery{q, err}
+	return q, err
+}
+
+func patterns(exprs ...string) []*regexp.Regexp {
+	out := make([]*regexp.Regexp, 0, len(exprs))
+	for _, e := range exprs {
+		out = append(out, regexp.MustCompile(e))
+	}
+	return out
 }
 
+const (
+	jsQuery   = `(comment) @comment ((comment) @doc (#match? @doc "^/\\*\\*[^/]"))`
+	jsxQuery  = jsQuery + ` (jsx_expression (comment)+) @container`
+	javaQuery = `(line_comment) @comment (block_comment) @comment ((block_comment) @doc (#match? @doc "^/\\*\\*[^/]"))`
+	cQuery    = `(comment) @comment ((comment) @doc (#match? @doc "^(///([^/]|$)|//!|/\\*\\*[^/]|/\\*!)"))`
+	luaQuery  = `(comment) @comment ((comment) @doc (#match? @doc "^\\s*---"))`
+	rustQuery = `(line_comment) @comment
+		(block_comment) @comment
+		(line_comment (outer_doc_comment_marker)) @doc
+		(line_comment (inner_doc_comment_marker)) @doc
+		(block_comment (outer_doc_comment_marker)) @doc
+		(block_comment (inner_doc_comment_marker)) @doc`
+	kotlinQuery = `(line_comment) @comment (multiline_comment) @comment ((multiline_comment) @doc (#match? @doc "^/\\*\\*[^/]"))`
+	scalaQuery  = `(comment) @comment (block_comment) @comment ((block_comment) @doc (#match? @doc "^/\\*\\*[^/]"))`
+	groovyQuery = `(shebang) @comment (comment) @comment (groovy_doc) @doc ((comment) @doc (#match? @doc "^/\\*\\*[^/]"))`
+	swiftQuery  = `(comment) @comment (multiline_comment) @comment
+		((comment) @doc (#match? @doc "^///([^/]|$)"))
+		((multiline_comment) @doc (#match? @doc "^/\\*\\*[^/]"))`
+	csharpQuery = `(comment) @comment ((comment) @doc (#match? @doc "^(///([^/]|$)|/\\*\\*[^/])"))`
+	ocamlQuery  = `(comment) @comment ((comment) @doc (#match? @doc "^\\(\\*\\*[^*)]"))`
+	elmQuery    = `(line_comment) @comment (block_comment) @comment ((block_comment) @doc (#match? @doc "^\\{-\\|"))`
+	phpQuery    = `(comment) @comment ((comment) @doc (#match? @doc "^/\\*\\*[^/]"))`
+	elixirQuery = `(comment) @comment
+		((unary_operator) @doc
+			(#match? @doc "^@(module|type)?doc\\b")
+			(#not-match? @doc "^@(module|type)?doc\\s+false$"))`
+	goQuery = `(comment) @comment
+		((comment) @doc . [
+			(package_clause)
+			(function_declaration)
+			(method_declaration)
+			(type_declaration)
+			(const_declaration)
+			(var_declaration)
+		])`
+	haskellQuery = `(comment) @comment
+		(haddock) @doc
+		(pragma) @directive`
+)
+
+var (
+	jsSuppressions = patterns(
+		`eslint-(disable|enable)`,
+		`@ts-(expect-error|ignore|nocheck)\b`,
+		`prettier-ignore`,
+		`(istanbul|c8) ignore`,
+		`biome-ignore`,
+	)
+	pythonSuppressions = patterns(
+		`#\s*noqa\b`,
+		`#\s*type:\s*ignore`,
+		`#\s*(pylint|pyright|mypy):`,
+		`#\s*pragma:\s*no\s*(cover|branch)`,
+		`#\s*fmt:\s*(off|on|skip)`,
+		`#\s*isort:\s*(skip|off|on)`,
+		`#\s*nosec\b`,
+	)
+	goSuppressions = patterns(
+		`^//\s*nolint\b`,
+		`^//lint:(file-)?ignore\b`,
+		`#nosec\b`,
+	)
+	cSuppressions = patterns(
+		`\bNOLINT(NEXTLINE|BEGIN|END)?\b`,
+		`clang-format\s+(off|on)`,
+		`cppcheck-suppress`,
+		`LCOV_EXCL_`,
+		`coverity\[`,
+	)
+	javaSuppressions = patterns(
+		`^//\s*noinspection\b`,
+		`CHECKSTYLE[:.]?\s*(OFF|ON)`,
+		`\bNOPMD\b`,
+		`@formatter:(off|on)`,
+	)
+	luaSuppressions = patterns(
+		`luacheck:`,
+		`@diagnostic\s+(disable|enable)`,
+		`stylua:\s*ignore`,
+	)
+	cssSuppressions = patterns(
+		`stylelint-(disable|enable)`,
+		`prettier-ignore`,
+	)
+	htmlSuppressions = patterns(
+		`prettier-ignore`,
+		`htmlhint\s`,
+	)
+	vueSuppressions = patterns(
+		`prettier-ignore`,
+		`eslint-(disable|enable)`,
+	)
+	svelteSuppressions = patterns(
+		`prettier-ignore`,
+		`svelte-ignore\s`,
+	)
+	yamlSuppressions = patterns(
+		`yamllint\s+(disable|enable)`,
+		`prettier-ignore`,
+	)
+	tomlSuppressions = patterns(
+		`^#:schema\s`,
+		`taplo:`,
+	)
+	shellSuppressions = patterns(
+		`shellcheck\s+(disable|enable|source|shell)=`,
+	)
+	rubySuppressions = patterns(
+		`rubocop:(disable|enable|todo)\b`,
+		`:nocov:`,
+		`steep:ignore\b`,
+	)
+	phpSuppressions = patterns(
+		`phpcs:(ignore|disable|enable)`,
+		`@phpstan-ignore`,
+		`@psalm-suppress\s`,
+		`@codeCoverageIgnore`,
+	)
+	elixirSuppressions = patterns(
+		`credo:disable-for-`,
+		`coveralls-ignore-`,
+	)
+	kotlinSuppressions = patterns(
+		`^//\s*noinspection\b`,
+		`ktlint-(disable|enable)`,
+		`@formatter:(off|on)`,
+	)
+	scalaSuppressions = patterns(
+		`scalastyle:(off|on|ignore)`,
+		`scalafix:(off|on|ok)`,
+		`^//\s*format:\s*(off|on)`,
+	)
+	groovySuppressions = patterns(
+		`codenarc-disable`,
+		`codenarc-enable`,
+		`^//\s*noinspection\b`,
+	)
+	swiftSuppressions = patterns(
+		`swiftlint:(disable|enable)`,
+		`swift-format-ignore`,
+	)
+	csharpSuppressions = patterns(
+		`<auto-generated`,
+		`ReSharper\s+(disable|restore)`,
+		`dotcover\s+(disable|enable)`,
+	)
+	hclSuppressions = patterns(
+		`tfsec:ignore:`,
+		`trivy:ignore:`,
+		`checkov:skip=`,
+		`tflint-ignore:`,
+	)
+	dockerfileSuppressions = patterns(
+		`hadolint\s+(ignore|global)`,
+	)
+	protoSuppressions = patterns(
+		`buf:lint:ignore\b`,
+	)
+	sqlSuppressions = patterns(
+		`--\s*noqa\b`,
+		`sqlfluff:`,
+	)
+	dartSuppressions = patterns(
+		`^//\s*ignore(_for_file)?:`,
+	)
+	haskellSuppressions = patterns(
+		`^\{-\s*HLINT\s+ignore\b`,
+		`^\{-\s*(ORMOLU|FOURMOLU)_(DISABLE|ENABLE)\s*-\}`,
+	)
+)
+
+type compiledQuery struct {
+	query *sitter.Query
+	err   error
+}
+
+var (
+	queryMu  sync.Mutex
+	compiled = map[string]compiledQuery{}
+)
+
 var byExtension = map[string]LangConfig{
 	".js": {
-		Name:     "javascript",
-		Query:    "(comment) @comment",
-		Language: javascript.GetLanguage,
+		Name:         "javascript",
+		Query:        jsxQuery,
+		Language:     javascript.GetLanguage,
+		Suppressions: jsSuppressions,
 	},
 	".mjs": {
-		Name:     "javascript",
-		Query:    "(comment) @comment",
-		Language: javascript.GetLanguage,
+		Name:         "javascript",
+		Query:        jsxQuery,
+		Language:     javascript.GetLanguage,
+		Suppressions: jsSuppressions,
 	},
 	".cjs": {
-		Name:     "javascript",
-		Query:    "(comment) @comment",
-		Language: javascript.GetLanguage,
+		Name:         "javascript",
+		Query:        jsxQuery,
+		Language:     javascript.GetLanguage,
+		Suppressions: jsSuppressions,
 	},
 	".jsx": {
-		Name:     "javascript",
-		Query:    "(comment) @comment",
-		Language: javascript.GetLanguage,
+		Name:         "javascript",
+		Query:        jsxQuery,
+		Language:     javascript.GetLanguage,
+		Suppressions: jsSuppressions,
 	},
 	".ts": {
-		Name:     "typescript",
-		Query:    "(comment) @comment",
-		Language: typescript.GetLanguage,
+		Name:         "typescript",
+		Query:        jsQuery,
+		Language:     typescript.GetLanguage,
+		Suppressions: jsSuppressions,
 	},
 	".tsx": {
-		Name:     "tsx",
-		Query:    "(comment) @comment",
-		Language: tsx.GetLanguage,
+		Name:         "tsx",
+		Query:        jsxQuery,
+		Language:     tsx.GetLanguage,
+		Suppressions: jsSuppressions,
 	},
 	".lua": {
-		Name:     "lua",
-		Query:    "(comment) @comment",
-		Language: lua.GetLanguage,
+		Name:         "lua",
+		Query:        luaQuery,
+		Language:     lua.GetLanguage,
+		Suppressions: luaSuppressions,
 	},
 	".py": {
-		Name:     "python",
-		Query:    "(comment) @comment",
-		Language: python.GetLanguage,
+		Name:         "python",
+		Query:        "(comment) @comment",
+		Language:     python.GetLanguage,
+		Suppressions: pythonSuppressions,
 	},
 	".go": {
-		Name:     "go",
-		Query:    "(comment) @comment",
-		Language: golang.GetLanguage,
+		Name:         "go",
+		Query:        goQuery,
+		Language:     golang.GetLanguage,
+		Suppressions: goSuppressions,
 	},
 	".java": {
-		Name:     "java",
-		Query:    "(line_comment) @comment (block_comment) @comment",
-		Language: java.GetLanguage,
+		Name:         "java",
+		Query:        javaQuery,
+		Language:     java.GetLanguage,
+		Suppressions: javaSuppressions,
 	},
 	".c": {
-		Name:     "c",
-		Query:    "(comment) @comment",
-		Language: c.GetLanguage,
+		Name:         "c",
+		Query:        cQuery,
+		Language:     c.GetLanguage,
+		Suppressions: cSuppressions,
 	},
 	".h": {
-		Name:     "c",
-		Query:    "(comment) @comment",
-		Language: c.GetLanguage,
+		Name:         "c",
+		Query:        cQuery,
+		Language:     c.GetLanguage,
+		Suppressions: cSuppressions,
 	},
 	".cpp": {
-		Name:     "cpp",
-		Query:    "(comment) @comment",
-		Language: cpp.GetLanguage,
+		Name:         "cpp",
+		Query:        cQuery,
+		Language:     cpp.GetLanguage,
+		Suppressions: cSuppressions,
 	},
 	".cc": {
-		Name:     "cpp",
-		Query:    "(comment) @comment",
-		Language: cpp.GetLanguage,
+		Name:         "cpp",
+		Query:        cQuery,
+		Language:     cpp.GetLanguage,
+		Suppressions: cSuppressions,
 	},
 	".cxx": {
-		Name:     "cpp",
-		Query:    "(comment) @comment",
-		Language: cpp.GetLanguage,
+		Name:         "cpp",
+		Query:        cQuery,
+		Language:     cpp.GetLanguage,
+		Suppressions: cSuppressions,
 	},
 	".hpp": {
-		Name:     "cpp",
-		Query:    "(comment) @comment",
-		Language: cpp.GetLanguage,
+		Name:         "cpp",
+		Query:        cQuery,
+		Language:     cpp.GetLanguage,
+		Suppressions: cSuppressions,
 	},
 	".rs": {
 		Name:     "rust",
-		Query:    "(line_comment) @comment",
+		Query:    rustQuery,
 		Language: rust.GetLanguage,
 	},
 	".html": {
-		Name:     "html",
-		Query:    "(comment) @comment",
-		Language: html.GetLanguage,
+		Name:         "html",
+		Query:        "(comment) @comment",
+		Language:     html.GetLanguage,
+		Suppressions: htmlSuppressions,
+		Markup:       true,
 	},
 	".htm": {
-		Name:     "html",
-		Query:    "(comment) @comment",
-		Language: html.GetLanguage,
+		Name:         "html",
+		Query:        "(comment) @comment",
+		Language:     html.GetLanguage,
+		Suppressions: htmlSuppressions,
+		Markup:       true,
+	},
+	".vue": {
+		Name:         "vue",
+		Query:        "(comment) @comment",
+		Language:     html.GetLanguage,
+		Suppressions: vueSuppressions,
+		Markup:       true,
+	},
+	".svelte": {
+		Name:         "svelte",
+		Query:        "(comment) @comment",
+		Language:     svelte.GetLanguage,
+		Suppressions: svelteSuppressions,
+		Markup:       true,
+	},
+	".scss": {
+		Name:         "scss",
+		Query:        "(comment) @comment (js_comment) @comment",
+		Language:     css.GetLanguage,
+		Suppressions: cssSuppressions,
 	},
 	".css": {
-		Name:     "css",
-		Query:    "(comment) @comment",
-		Language: css.GetLanguage,
+		Name:         "css",
+		Query:        "(comment) @comment",
+		Language:     css.GetLanguage,
+		Suppressions: cssSuppressions,
 	},
 	".yaml": {
-		Name:     "yaml",
-		Query:    "(comment) @comment",
-		Language: yaml.GetLanguage,
+		Name:         "yaml",
+		Query:        "(comment) @comment",
+		Language:     yaml.GetLanguage,
+		Suppressions: yamlSuppressions,
 	},
 	".yml": {
-		Name:     "yaml",
-		Query:    "(comment) @comment",
-		Language: yaml.GetLanguage,
+		Name:         "yaml",
+		Query:        "(comment) @comment",
+		Language:     yaml.GetLanguage,
+		Suppressions: yamlSuppressions,
 	},
 	".toml": {
-		Name:     "toml",
-		Query:    "(comment) @comment",
-		Language: toml.GetLanguage,
+		Name:         "toml",
+		Query:        "(comment) @comment",
+		Language:     toml.GetLanguage,
+		Suppressions: tomlSuppressions,
 	},
 	".sh": {
-		Name:     "bash",
-		Query:    "(comment) @comment",
-		Language: bash.GetLanguage,
+		Name:         "bash",
+		Query:        "(comment) @comment",
+		Language:     bash.GetLanguage,
+		Suppressions: shellSuppressions,
 	},
 	".bash": {
-		Name:     "bash",
+		Name:         "bash",
+		Query:        "(comment) @comment",
+		Language:     bash.GetLanguage,
+		Suppressions: shellSuppressions,
+	},
+	".rb": {
+		Name:         "ruby",
+		Query:        "(comment) @comment",
+		Language:     ruby.GetLanguage,
+		Suppressions: rubySuppressions,
+	},
+	".rake": {
+		Name:         "ruby",
+		Query:        "(comment) @comment",
+		Language:     ruby.GetLanguage,
+		Suppressions: rubySuppressions,
+	},
+	".php": {
+		Name:         "php",
+		Query:        phpQuery,
+		Language:     php.GetLanguage,
+		Suppressions: phpSuppressions,
+	},
+	".ex": {
+		Name:         "elixir",
+		Query:        elixirQuery,
+		Language:     elixir.GetLanguage,
+		Suppressions: elixirSuppressions,
+	},
+	".exs": {
+		Name:         "elixir",
+		Query:        elixirQuery,
+		Language:     elixir.GetLanguage,
+		Suppressions: elixirSuppressions,
+	},
+	".kt": {
+		Name:         "kotlin",
+		Query:        kotlinQuery,
+		Language:     kotlin.GetLanguage,
+		Suppressions: kotlinSuppressions,
+	},
+	".kts": {
+		Name:         "kotlin",
+		Query:        kotlinQuery,
+		Language:     kotlin.GetLanguage,
+		Suppressions: kotlinSuppressions,
+	},
+	".scala": {
+		Name:         "scala",
+		Query:        scalaQuery,
+		Language:     scala.GetLanguage,
+		Suppressions: scalaSuppressions,
+	},
+	".sc": {
+		Name:         "scala",
+		Query:        scalaQuery,
+		Language:     scala.GetLanguage,
+		Suppressions: scalaSuppressions,
+	},
+	".groovy": {
+		Name:         "groovy",
+		Query:        groovyQuery,
+		Language:     groovy.GetLanguage,
+		Suppressions: groovySuppressions,
+	},
+	".gradle": {
+		Name:         "groovy",
+		Query:        groovyQuery,
+		Language:     groovy.GetLanguage,
+		Suppressions: groovySuppressions,
+	},
+	".swift": {
+		Name:         "swift",
+		Query:        swiftQuery,
+		Language:     swift.GetLanguage,
+		Suppressions: swiftSuppressions,
+	},
+	".cs": {
+		Name:         "csharp",
+		Query:        csharpQuery,
+		Language:     csharp.GetLanguage,
+		Suppressions: csharpSuppressions,
+	},
+	".tf": {
+		Name:         "hcl",
+		Query:        "(comment) @comment",
+		Language:     hcl.GetLanguage,
+		Suppressions: hclSuppressions,
+	},
+	".tfvars": {
+		Name:         "hcl",
+		Query:        "(comment) @comment",
+		Language:     hcl.GetLanguage,
+		Suppressions: hclSuppressions,
+	},
+	".hcl": {
+		Name:         "hcl",
+		Query:        "(comment) @comment",
+		Language:     hcl.GetLanguage,
+		Suppressions: hclSuppressions,
+	},
+	".dockerfile": {
+		Name:         "dockerfile",
+		Query:        "(comment) @comment",
+		Language:     dockerfile.GetLanguage,
+		Suppressions: dockerfileSuppressions,
+	},
+	".proto": {
+		Name:         "protobuf",
+		Query:        "(comment) @comment",
+		Language:     protobuf.GetLanguage,
+		Suppressions: protoSuppressions,
+	},
+	".sql": {
+		Name:         "sql",
+		Query:        "(comment) @comment (marginalia) @comment",
+		Language:     sql.GetLanguage,
+		Suppressions: sqlSuppressions,
+	},
+	".cue": {
+		Name:     "cue",
 		Query:    "(comment) @comment",
-		Language: bash.GetLanguage,
+		Language: cue.GetLanguage,
+	},
+	".ml": {
+		Name:     "ocaml",
+		Query:    ocamlQuery,
+		Language: ocaml.GetLanguage,
+	},
+	".elm": {
+		Name:     "elm",
+		Query:    elmQuery,
+		Language: elm.GetLanguage,
+	},
+	".hs": {
+		Name:         "haskell",
+		Query:        haskellQuery,
+		Language:     haskell.GetLanguage,
+		Suppressions: haskellSuppressions,
 	},
 	".dart": {
-		Name:     "dart",
-		Query:    "(comment) @comment (documentation_comment) @comment",
-		Language: dart.GetLanguage,
+		Name:         "dart",
+		Query:        "(comment) @comment (documentation_comment) @doc",
+		Language:     dart.GetLanguage,
+		Suppressions: dartSuppressions,
 	},
 }
 
+var aliased = map[string]bool{}
+
+var byFilename = map[string]string{
+	"Gemfile":       ".rb",
+	"Rakefile":      ".rb",
+	"Vagrantfile":   ".rb",
+	"Podfile":       ".rb",
+	"Brewfile":      ".rb",
+	"Guardfile":     ".rb",
+	"Capfile":       ".rb",
+	"Fastfile":      ".rb",
+	"Dockerfile":    ".dockerfile",
+	"Containerfile": ".dockerfile",
+	"Jenkinsfile":   ".groovy",
+	"PKGBUILD":      ".sh",
+	"APKBUILD":      ".sh",
+	".bashrc":       ".sh",
+	".bash_profile": ".sh",
+	".bash_login":   ".sh",
+	".bash_logout":  ".sh",
+	".bash_aliases": ".sh",
+	".profile":      ".sh",
+	".envrc":        ".sh",
+	".zshrc":        ".sh",
+	".zshenv":       ".sh",
+	".zprofile":     ".sh",
+	".zlogin":       ".sh",
+	".zlogout":      ".sh",
+}
+
+var filenamePatterns = []struct {
+	glob   string
+	except string
+	ext    string
+}{
+	{"Dockerfile.*", "*.dockerignore", ".dockerfile"},
+	{"*.Dockerfile", "", ".dockerfile"},
+	{"Containerfile.*", "*.dockerignore", ".dockerfile"},
+}
+
 func Get(ext string) (LangConfig, bool) {
 	cfg, ok := byExtension[ext]
 	return cfg, ok
 }
 
+func ByName(name string) (LangConfig, bool) {
+	for _, ext := range Supported() {
+		if cfg := byExtension[ext]; cfg.Name == name {
+			return cfg, true
+		}
+	}
+	return LangConfig{}, false
+}
+
+func Alias(ext, name string) error {
+	cfg, ok := ByName(name)
+	if !ok {
+		return fmt.Errorf("unknown language %q for %s", name, ext)
+	}
+	byExtension[ext] = cfg
+	aliased[ext] = true
+	return nil
+}
+
+func Aliased(ext string) bool {
+	return aliased[ext]
+}
+
+func SetQuery(name, query string) error {
+	found := false
+	for ext, cfg := range byExtension {
+		if cfg.Name == name {
+			cfg.Query = query
+			byExtension[ext] = cfg
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("unknown language %q", name)
+	}
+	return nil
+}
+
 func Supported() []string {
 	exts := make([]string, 0, len(byExtension))
 	for ext := range byExtension {
 		exts = append(exts, ext)
 	}
+	sort.Strings(exts)
 	return exts
 }
+
+func ForFile(path string) (LangConfig, bool) {
+	if cfg, ok := ForFilename(path); ok {
+		return cfg, true
+	}
+	return Get(filepath.Ext(path))
+}
+
+func ForFilename(path string) (LangConfig, bool) {
+	base := filepath.Base(path)
+	if ext, ok := byFilename[base]; ok {
+		return Get(ext)
+	}
+	for _, p := range filenamePatterns {
+		matched, _ := filepath.Match(p.glob, base)
+		excluded, _ := filepath.Match(p.except, base)
+		if matched && !excluded {
+			return Get(p.ext)
+		}
+	}
+	return LangConfig{}, false
+}
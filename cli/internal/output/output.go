@@ -20,10 +20,11 @@ type Printer struct {
 	quiet    bool
 	write    bool
 	showDiff bool
+	verbose  bool
 }
 
-func New(w io.Writer, quiet, write, showDiff bool) *Printer {
-	return &Printer{w: w, quiet: quiet, write: write, showDiff: showDiff}
+func New(w io.Writer, quiet, write, showDiff, verbose bool) *Printer {
+	return &Printer{w: w, quiet: quiet, write: write, showDiff: showDiff, verbose: verbose}
 }
 
 func (p *Printer) File(r diff.Result) {
@@ -34,19 +35,37 @@ func (p *Printer) File(r diff.Result) {
 	if p.write {
 		action = "removed"
 	}
-	removed := r.LinesRemoved()
+	removed := r.CommentLinesRemoved()
 	noun := "line"
 	if removed != 1 {
 		noun = "lines"
 	}
 	_, _ = yellow.Fprintf(p.w, "  %s  ", action)
-	_, _ = fmt.Fprintf(p.w, "%d comment %s from ", removed, noun)
+	_, _ = fmt.Fprintf(p.w, "%d comment %s ", removed, noun)
+	if r.BlankLines > 0 {
+		noun = "line"
+		if r.BlankLines != 1 {
+			noun = "lines"
+		}
+		_, _ = fmt.Fprintf(p.w, "and %d blank %s ", r.BlankLines, noun)
+	}
+	_, _ = fmt.Fprint(p.w, "from ")
 	_, _ = bold.Fprintf(p.w, "%s\n", r.Path)
 	if p.showDiff {
 		_, _ = fmt.Fprint(p.w, r.Unified())
 	}
 }
 
+func (p *Printer) Language(path, lang, reason string) {
+	if p.quiet || !p.verbose {
+		return
+	}
+	if reason != "" {
+		lang = fmt.Sprintf("%s (%s)", lang, reason)
+	}
+	_, _ = fmt.Fprintf(p.w, "  lang  %s: %s\n", path, lang)
+}
+
 func (p *Printer) Skipped(path, reason string) {
 	if p.quiet {
 		return
@@ -54,21 +73,45 @@ func (p *Printer) Skipped(path, reason string) {
 	_, _ = fmt.Fprintf(p.w, "  skip  %s (%s)\n", path, reason)
 }
 
+func (p *Printer) Suppression(path string, line int, text string) {
+	_, _ = yellow.Fprintf(p.w, "  suppression  ")
+	_, _ = fmt.Fprintf(p.w, "%s:%d: %s\n", path, line, text)
+}
+
 func (p *Printer) Error(path string, err error) {
 	_, _ = red.Fprintf(p.w, "  error  %s: %v\n", path, err)
 }
 
-func (p *Printer) Summary(changed, skipped, errors, total int) {
+type Totals struct {
+	Changed   int
+	Kept      int
+	Protected int
+	Skipped   int
+	Errors    int
+	Total     int
+}
+
+func (p *Printer) Summary(t Totals) {
 	action := "would be modified"
 	if p.write {
 		action = "modified"
 	}
-	_, _ = bold.Fprintf(p.w, "\n%d/%d files %s", changed, total, action)
-	if skipped > 0 {
-		_, _ = fmt.Fprintf(p.w, ", %d skipped", skipped)
+	_, _ = bold.Fprintf(p.w, "\n%d/%d files %s", t.Changed, t.Total, action)
+	if t.Kept > 0 {
+		noun := "comment"
+		if t.Kept != 1 {
+			noun = "comments"
+		}
+		_, _ = fmt.Fprintf(p.w, ", %d %s kept", t.Kept, noun)
+	}
+	if t.Protected > 0 {
+		_, _ = fmt.Fprintf(p.w, ", %d protected", t.Protected)
+	}
+	if t.Skipped > 0 {
+		_, _ = fmt.Fprintf(p.w, ", %d skipped", t.Skipped)
 	}
-	if errors > 0 {
-		_, _ = red.Fprintf(p.w, ", %d errors", errors)
+	if t.Errors > 0 {
+		_, _ = red.Fprintf(p.w, ", %d errors", t.Errors)
 	}
 	_, _ = fmt.Fprintln(p.w)
 }
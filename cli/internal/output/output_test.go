@@ -17,7 +17,7 @@ func init() {
 
 func TestPrinter_File_Quiet_NoOutput(t *testing.T) {
 	var buf bytes.Buffer
-	p := New(&buf, true, false, false)
+	p := New(&buf, true, false, false, false)
 	r := diff.Compute("foo.go", []byte("// c\nx\n"), []byte("x\n"))
 	p.File(r)
 	if buf.Len() != 0 {
@@ -27,7 +27,7 @@ func TestPrinter_File_Quiet_NoOutput(t *testing.T) {
 
 func TestPrinter_File_Unchanged_NoOutput(t *testing.T) {
 	var buf bytes.Buffer
-	p := New(&buf, false, false, false)
+	p := New(&buf, false, false, false, false)
 	r := diff.Compute("foo.go", []byte("x\n"), []byte("x\n"))
 	p.File(r)
 	if buf.Len() != 0 {
@@ -37,7 +37,7 @@ func TestPrinter_File_Unchanged_NoOutput(t *testing.T) {
 
 func TestPrinter_File_DryRun_ContainsWouldRemove(t *testing.T) {
 	var buf bytes.Buffer
-	p := New(&buf, false, false, false)
+	p := New(&buf, false, false, false, false)
 	r := diff.Compute("foo.go", []byte("// comment\nx\n"), []byte("x\n"))
 	p.File(r)
 	if !strings.Contains(buf.String(), "would remove") {
@@ -47,7 +47,7 @@ func TestPrinter_File_DryRun_ContainsWouldRemove(t *testing.T) {
 
 func TestPrinter_File_WriteMode_ContainsRemoved(t *testing.T) {
 	var buf bytes.Buffer
-	p := New(&buf, false, true, false)
+	p := New(&buf, false, true, false, false)
 	r := diff.Compute("foo.go", []byte("// comment\nx\n"), []byte("x\n"))
 	p.File(r)
 	if !strings.Contains(buf.String(), "removed") {
@@ -57,7 +57,7 @@ func TestPrinter_File_WriteMode_ContainsRemoved(t *testing.T) {
 
 func TestPrinter_File_ShowDiff_ContainsMinusLine(t *testing.T) {
 	var buf bytes.Buffer
-	p := New(&buf, false, false, true)
+	p := New(&buf, false, false, true, false)
 	r := diff.Compute("foo.go", []byte("// comment\nx\n"), []byte("x\n"))
 	p.File(r)
 	if !strings.Contains(buf.String(), "-// comment") {
@@ -65,9 +65,43 @@ func TestPrinter_File_ShowDiff_ContainsMinusLine(t *testing.T) {
 	}
 }
 
+func TestPrinter_File_ReportsBlankLines(t *testing.T) {
+	var buf bytes.Buffer
+	p := New(&buf, false, false, false, false)
+	r := diff.Compute("foo.go", []byte("x\n\n// c\n\ny\n"), []byte("x\n\ny\n"))
+	r.BlankLines = 1
+	p.File(r)
+	if !strings.Contains(buf.String(), "1 comment line and 1 blank line from") {
+		t.Errorf("unexpected output %q", buf.String())
+	}
+}
+
+func TestPrinter_Language_VerboseOnly(t *testing.T) {
+	var buf bytes.Buffer
+	New(&buf, false, false, false, false).Language("a.h", "cpp", "namespace")
+	if buf.Len() != 0 {
+		t.Errorf("expected no output without verbose, got %q", buf.String())
+	}
+
+	New(&buf, false, false, false, true).Language("a.h", "cpp", "namespace")
+	New(&buf, false, false, false, true).Language("b.go", "go", "")
+	if got := buf.String(); got != "  lang  a.h: cpp (namespace)\n  lang  b.go: go\n" {
+		t.Errorf("unexpected output %q", got)
+	}
+}
+
+func TestPrinter_Suppression_AlwaysPrints(t *testing.T) {
+	var buf bytes.Buffer
+	p := New(&buf, true, false, false, false)
+	p.Suppression("app.py", 3, "# noqa: E501")
+	if !strings.Contains(buf.String(), "app.py:3: # noqa: E501") {
+		t.Errorf("expected suppression location and text, got %q", buf.String())
+	}
+}
+
 func TestPrinter_Error_AlwaysPrints(t *testing.T) {
 	var buf bytes.Buffer
-	p := New(&buf, true, false, false)
+	p := New(&buf, true, false, false, false)
 	p.Error("bar.go", fmt.Errorf("some error"))
 	if !strings.Contains(buf.String(), "bar.go") {
 		t.Errorf("expected path in error output, got %q", buf.String())
@@ -76,10 +110,39 @@ func TestPrinter_Error_AlwaysPrints(t *testing.T) {
 
 func TestPrinter_Summary_ContainsCount(t *testing.T) {
 	var buf bytes.Buffer
-	p := New(&buf, false, false, false)
-	p.Summary(3, 1, 0, 10)
+	p := New(&buf, false, false, false, false)
+	p.Summary(Totals{Changed: 3, Skipped: 1, Total: 10})
 	out := buf.String()
 	if !strings.Contains(out, "3/10") {
 		t.Errorf("expected '3/10' in summary, got %q", out)
 	}
 }
+
+func TestPrinter_Summary_ReportsKept(t *testing.T) {
+	var buf bytes.Buffer
+	p := New(&buf, true, false, false, false)
+	p.Summary(Totals{Changed: 1, Kept: 4, Total: 2})
+	out := buf.String()
+	if !strings.Contains(out, "4 comments kept") {
+		t.Errorf("expected kept count in summary, got %q", out)
+	}
+}
+
+func TestPrinter_Summary_NoKept_Omitted(t *testing.T) {
+	var buf bytes.Buffer
+	p := New(&buf, false, false, false, false)
+	p.Summary(Totals{Changed: 1, Total: 2})
+	if strings.Contains(buf.String(), "kept") {
+		t.Errorf("expected no kept count in summary, got %q", buf.String())
+	}
+}
+
+func TestPrinter_Summary_ReportsProtected(t *testing.T) {
+	var buf bytes.Buffer
+	p := New(&buf, false, false, false, false)
+	p.Summary(Totals{Changed: 1, Kept: 1, Protected: 3, Total: 1})
+	out := buf.String()
+	if !strings.Contains(out, "1 comment kept, 3 protected") {
+		t.Errorf("expected kept and protected counts in summary, got %q", out)
+	}
+}
@@ -0,0 +1,28 @@
+package parser
+
+import (
+	"regexp"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+var dockerParserDirective = regexp.MustCompile(`(?i)^#\s*(syntax|escape|check)\s*=`)
+
+func markDockerfile(src []byte, nodes []*sitter.Node, ranges []CommentRange) {
+	for i := range nodes {
+		if ranges[i].StartRow != uint32(i) || !dockerParserDirective.Match(ranges[i].Text(src)) {
+			break
+		}
+		ranges[i].Protection = ProtectDirective
+		ranges[i].Kind = KindDirective
+	}
+}
+
+func startsLine(src []byte, n *sitter.Node) bool {
+	for i := int(n.StartByte()) - 1; i >= 0 && src[i] != '\n'; i-- {
+		if !isSpace(src[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,134 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+var (
+	goBuildConstraint = regexp.MustCompile(`^//(go:build |\s*\+build )`)
+	goDirective       = regexp.MustCompile(`^//(go:[a-z]|line )|^/\*line `)
+	goExampleOutput   = regexp.MustCompile(`^//\s*(Unordered output|Output):`)
+)
+
+func markGo(src []byte, nodes []*sitter.Node, ranges []CommentRange) {
+	index := make(map[uint32]int, len(nodes))
+	for i, n := range nodes {
+		index[n.StartByte()] = i
+	}
+	for i, n := range nodes {
+		if ranges[i].Kind != KindDoc {
+			continue
+		}
+		next := n.NextNamedSibling()
+		if next == nil || next.StartPoint().Row > n.EndPoint().Row+1 {
+			ranges[i].Kind = shapeKind(ranges[i].Text(src))
+			continue
+		}
+		cur := n
+		for prev := cur.PrevNamedSibling(); prev != nil && prev.Type() == "comment"; prev = prev.PrevNamedSibling() {
+			if prev.EndPoint().Row+1 < cur.StartPoint().Row {
+				break
+			}
+			if j, ok := index[prev.StartByte()]; ok {
+				ranges[j].Kind = KindDoc
+			}
+			cur = prev
+		}
+	}
+	for i, n := range nodes {
+		if isGoDirective(src, n) {
+			ranges[i].Protection = ProtectDirective
+			ranges[i].Kind = KindDirective
+		}
+	}
+}
+
+func isGoDirective(src []byte, n *sitter.Node) bool {
+	text := n.Content(src)
+	switch {
+	case goBuildConstraint.MatchString(text):
+		return precedesPackageClause(n)
+	case strings.HasPrefix(text, "//go:embed "):
+		return declares(n, "var_declaration", "var_spec")
+	case strings.HasPrefix(text, "//export "):
+		return n.StartPoint().Column == 0 && declares(n, "function_declaration")
+	case goDirective.MatchString(text):
+		return n.StartPoint().Column == 0 || strings.HasPrefix(text, "/*")
+	}
+	return precedesImportC(src, n) || inExampleOutput(src, n)
+}
+
+func precedesPackageClause(n *sitter.Node) bool {
+	for prev := n.PrevNamedSibling(); prev != nil; prev = prev.PrevNamedSibling() {
+		if prev.Type() != "comment" {
+			return false
+		}
+	}
+	return n.Parent() != nil && n.Parent().Type() == "source_file"
+}
+
+func declares(n *sitter.Node, types ...string) bool {
+	next := n.NextNamedSibling()
+	for next != nil && next.Type() == "comment" {
+		next = next.NextNamedSibling()
+	}
+	if next == nil {
+		return false
+	}
+	for _, t := range types {
+		if next.Type() == t {
+			return true
+		}
+	}
+	return false
+}
+
+func precedesImportC(src []byte, n *sitter.Node) bool {
+	cur := n
+	for {
+		next := cur.NextNamedSibling()
+		if next == nil || next.StartPoint().Row > cur.EndPoint().Row+1 {
+			return false
+		}
+		if next.Type() == "import_declaration" {
+			return importsC(src, next)
+		}
+		if next.Type() != "comment" {
+			return false
+		}
+		cur = next
+	}
+}
+
+func importsC(src []byte, decl *sitter.Node) bool {
+	spec := decl.NamedChild(0)
+	if spec == nil || spec.Type() != "import_spec" {
+		return false
+	}
+	path := spec.ChildByFieldName("path")
+	return path != nil && path.Content(src) == `"C"`
+}
+
+func inExampleOutput(src []byte, n *sitter.Node) bool {
+	block := n.Parent()
+	if block == nil || block.Type() != "block" {
+		return false
+	}
+	fn := block.Parent()
+	if fn == nil || fn.Type() != "function_declaration" {
+		return false
+	}
+	name := fn.ChildByFieldName("name")
+	if name == nil || !strings.HasPrefix(name.Content(src), "Example") {
+		return false
+	}
+	for cur := n; cur != nil && cur.Type() == "comment"; cur = cur.PrevNamedSibling() {
+		if goExampleOutput.MatchString(cur.Content(src)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,21 @@
+package parser
+
+import (
+	"regexp"
+)
+
+var groovyOneLineDoc = regexp.MustCompile(`/\*\*[^\n]*?\*/`)
+
+func groovySource(src []byte) []byte {
+	locs := groovyOneLineDoc.FindAllIndex(src, -1)
+	if len(locs) == 0 {
+		return src
+	}
+	out := append([]byte(nil), src...)
+	for _, loc := range locs {
+		if loc[1]-loc[0] > len("/**/") {
+			out[loc[0]+2] = ' '
+		}
+	}
+	return out
+}
@@ -0,0 +1,13 @@
+package parser
+
+import (
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+func markHaskell(src []byte, nodes []*sitter.Node, ranges []CommentRange) {
+	for i, n := range nodes {
+		if n.Type() == "pragma" {
+			ranges[i].Protection = ProtectDirective
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package parser
+
+import (
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+
+	"github.com/KashifKhn/remove-comments/cli/internal/languages"
+)
+
+type Injection struct {
+	StartByte uint32
+	EndByte   uint32
+	Start     sitter.Point
+	Lang      languages.LangConfig
+}
+
+var injectionRules = map[string]func(src []byte, root *sitter.Node) []Injection{
+	"html":   htmlInjections,
+	"vue":    htmlInjections,
+	"svelte": htmlInjections,
+	"php":    phpInjections,
+}
+
+var scriptTypes = map[string]bool{
+	"":                       true,
+	"module":                 true,
+	"text/javascript":        true,
+	"application/javascript": true,
+	"text/ecmascript":        true,
+	"application/ecmascript": true,
+}
+
+var scriptLangs = map[string]string{
+	"js":         ".js",
+	"javascript": ".js",
+	"jsx":        ".jsx",
+	"ts":         ".ts",
+	"typescript": ".ts",
+	"tsx":        ".tsx",
+}
+
+var styleLangs = map[string]string{
+	"css":     ".css",
+	"postcss": ".css",
+	"scss":    ".scss",
+}
+
+func Injections(src []byte, lang string, root *sitter.Node) []Injection {
+	rule, ok := injectionRules[lang]
+	if !ok {
+		return nil
+	}
+	return rule(src, root)
+}
+
+func (inj Injection) shift(r CommentRange) CommentRange {
+	if r.StartRow == 0 {
+		r.StartCol += inj.Start.Column
+	}
+	if r.EndRow == 0 {
+		r.EndCol += inj.Start.Column
+	}
+	r.StartRow += inj.Start.Row
+	r.EndRow += inj.Start.Row
+	r.StartByte += inj.StartByte
+	r.EndByte += inj.StartByte
+	return r
+}
+
+func htmlInjections(src []byte, root *sitter.Node) []Injection {
+	var out []Injection
+	var visit func(n *sitter.Node)
+	visit = func(n *sitter.Node) {
+		for i := 0; i < int(n.NamedChildCount()); i++ {
+			child := n.NamedChild(i)
+			switch child.Type() {
+			case "script_element", "style_element":
+				if inj, ok := htmlInjection(src, child); ok {
+					out = append(out, inj)
+				}
+			default:
+				visit(child)
+			}
+		}
+	}
+	visit(root)
+	return out
+}
+
+func phpInjections(src []byte, root *sitter.Node) []Injection {
+	cfg, ok := languages.Get(".html")
+	if !ok {
+		return nil
+	}
+	var out []Injection
+	var visit func(n *sitter.Node)
+	visit = func(n *sitter.Node) {
+		for i := 0; i < int(n.NamedChildCount()); i++ {
+			child := n.NamedChild(i)
+			if child.Type() != "text" {
+				visit(child)
+				continue
+			}
+			if t := n.Type(); t == "program" || t == "text_interpolation" {
+				out = append(out, Injection{
+					StartByte: child.StartByte(),
+					EndByte:   child.EndByte(),
+					Start:     child.StartPoint(),
+					Lang:      cfg,
+				})
+			}
+		}
+	}
+	visit(root)
+	return out
+}
+
+func htmlInjection(src []byte, el *sitter.Node) (Injection, bool) {
+	var tag, raw *sitter.Node
+	for i := 0; i < int(el.NamedChildCount()); i++ {
+		switch child := el.NamedChild(i); child.Type() {
+		case "start_tag":
+			tag = child
+		case "raw_text":
+			raw = child
+		}
+	}
+	if tag == nil || raw == nil {
+		return Injection{}, false
+	}
+
+	ext := injectedExt(el.Type(), tagAttributes(src, tag))
+	if ext == "" {
+		return Injection{}, false
+	}
+	cfg, ok := languages.Get(ext)
+	if !ok {
+		return Injection{}, false
+	}
+	return Injection{
+		StartByte: raw.StartByte(),
+		EndByte:   raw.EndByte(),
+		Start:     raw.StartPoint(),
+		Lang:      cfg,
+	}, true
+}
+
+func injectedExt(element string, attrs map[string]string) string {
+	kind := strings.ToLower(attrs["type"])
+	lang, hasLang := attrs["lang"]
+	lang = strings.ToLower(lang)
+	if element == "script_element" {
+		switch {
+		case hasLang:
+			return scriptLangs[lang]
+		case scriptTypes[kind]:
+			return ".js"
+		}
+		return ""
+	}
+	switch {
+	case hasLang:
+		return styleLangs[lang]
+	case kind == "" || kind == "text/css":
+		return ".css"
+	}
+	return ""
+}
+
+func tagAttributes(src []byte, tag *sitter.Node) map[string]string {
+	attrs := map[string]string{}
+	for i := 0; i < int(tag.NamedChildCount()); i++ {
+		attr := tag.NamedChild(i)
+		if attr.Type() != "attribute" {
+			continue
+		}
+		var name, value string
+		for j := 0; j < int(attr.NamedChildCount()); j++ {
+			switch part := attr.NamedChild(j); part.Type() {
+			case "attribute_name":
+				name = strings.ToLower(part.Content(src))
+			case "attribute_value":
+				value = part.Content(src)
+			case "quoted_attribute_value":
+				value = strings.Trim(part.Content(src), `"'`)
+			}
+		}
+		attrs[name] = strings.TrimSpace(value)
+	}
+	return attrs
+}
@@ -0,0 +1,81 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+type Kind uint8
+
+const (
+	KindLine Kind = iota
+	KindBlock
+	KindDoc
+	KindDirective
+	KindLicense
+	KindShebang
+)
+
+var kindNames = []string{"line", "block", "doc", "directive", "license", "shebang"}
+
+var blockOpeners = [][]byte{
+	[]byte("/*"),
+	[]byte("<!--"),
+	[]byte("--[["),
+	[]byte("--[="),
+	[]byte("{-"),
+	[]byte("(*"),
+	[]byte("=begin"),
+}
+
+func (k Kind) String() string {
+	if int(k) < len(kindNames) {
+		return kindNames[k]
+	}
+	return fmt.Sprintf("kind(%d)", k)
+}
+
+func ParseKind(s string) (Kind, error) {
+	for i, name := range kindNames {
+		if strings.EqualFold(s, name) {
+			return Kind(i), nil
+		}
+	}
+	return KindLine, fmt.Errorf("unknown comment kind %q (want one of %s)", s, strings.Join(kindNames, ", "))
+}
+
+func ParseKinds(names []string) (map[Kind]bool, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	kinds := map[Kind]bool{}
+	for _, name := range names {
+		k, err := ParseKind(strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+		kinds[k] = true
+	}
+	return kinds, nil
+}
+
+func shapeKind(text []byte) Kind {
+	for _, opener := range blockOpeners {
+		if bytes.HasPrefix(text, opener) {
+			return KindBlock
+		}
+	}
+	return KindLine
+}
+
+func captureKind(name string, text []byte) Kind {
+	if name == "comment" {
+		return shapeKind(text)
+	}
+	k, err := ParseKind(name)
+	if err != nil {
+		return shapeKind(text)
+	}
+	return k
+}
@@ -0,0 +1,67 @@
+package parser
+
+import (
+	"bytes"
+	"regexp"
+)
+
+var licenseMarker = regexp.MustCompile(`(?i)SPDX-License-Identifier|Copyright|©`)
+
+var importantCommentLangs = map[string]bool{
+	"css":        true,
+	"javascript": true,
+	"typescript": true,
+	"tsx":        true,
+}
+
+func markLicense(src []byte, lang string, ranges []CommentRange) {
+	header := 0
+	prevEnd := uint32(0)
+	if bytes.HasPrefix(src, bom) {
+		prevEnd = uint32(len(bom))
+	}
+	for _, r := range ranges {
+		if r.StartByte < prevEnd || int(r.StartByte) > len(src) {
+			break
+		}
+		if len(bytes.TrimSpace(src[prevEnd:r.StartByte])) > 0 {
+			break
+		}
+		header++
+		prevEnd = r.EndByte
+	}
+
+	license := make([]bool, header)
+	for i := 0; i < header; i++ {
+		if !isLicense(src, lang, ranges[i]) {
+			continue
+		}
+		license[i] = true
+		for j := i - 1; j >= 0 && continues(src, ranges[j], ranges[j+1]); j-- {
+			license[j] = true
+		}
+		for j := i + 1; j < header && continues(src, ranges[j-1], ranges[j]); j++ {
+			license[j] = true
+		}
+	}
+	for i, ok := range license {
+		if ok && ranges[i].Kind != KindShebang {
+			ranges[i].Syntax = shapeKind(ranges[i].Text(src))
+			ranges[i].Kind = KindLicense
+		}
+	}
+}
+
+func continues(src []byte, prev, next CommentRange) bool {
+	return prev.Kind != KindShebang && next.StartRow == prev.EndRow+1 &&
+		!prev.IsMultiLine && !next.IsMultiLine &&
+		shapeKind(prev.Text(src)) == KindLine && shapeKind(next.Text(src)) == KindLine
+}
+
+func isLicense(src []byte, lang string, r CommentRange) bool {
+	text := r.Text(src)
+	if licenseMarker.Match(text) {
+		return true
+	}
+	return importantCommentLangs[lang] && bytes.HasPrefix(text, []byte("/*!"))
+}
@@ -0,0 +1,61 @@
+package parser
+
+import (
+	"bytes"
+	"regexp"
+)
+
+const (
+	modelineWindow    = 5
+	emacsLocalsWindow = 3000
+)
+
+var (
+	bom               = []byte("\xef\xbb\xbf")
+	encodingDecl      = regexp.MustCompile(`^[ \t\f]*#.*?coding[:=][ \t]*[-\w.]+`)
+	vimModeline       = regexp.MustCompile(`(^|\s)(vi|vim|ex)([<=>]?\d+)?:\s*(set?\s+)?\w`)
+	emacsModeline     = regexp.MustCompile(`-\*-.+-\*-`)
+	emacsLocalsStart  = regexp.MustCompile(`Local Variables:`)
+	emacsLocalsFinish = regexp.MustCompile(`(^|\W)End:`)
+)
+
+func markMagic(src []byte, lines []string, ranges []CommentRange) {
+	lastRow := uint32(len(lines) - 1)
+	if lastRow > 0 && lines[lastRow] == "" {
+		lastRow--
+	}
+	inLocals := false
+	for i := range ranges {
+		r := &ranges[i]
+		text := r.Text(src)
+		nearTop := r.StartRow < modelineWindow
+		nearBottom := r.EndRow+modelineWindow > lastRow
+		switch {
+		case r.Protection != Unprotected:
+		case isShebang(src, r):
+			r.Protection = ProtectMagic
+			r.Kind = KindShebang
+		case r.StartRow <= 1 && (encodingDecl.Match(text) || emacsModeline.Match(text)):
+			r.Protection = ProtectMagic
+			r.Kind = KindDirective
+		case (nearTop || nearBottom) && vimModeline.Match(text):
+			r.Protection = ProtectMagic
+			r.Kind = KindDirective
+		case inLocals || (len(src)-int(r.StartByte) <= emacsLocalsWindow && emacsLocalsStart.Match(text)):
+			r.Protection = ProtectMagic
+			r.Kind = KindDirective
+			inLocals = !emacsLocalsFinish.Match(text)
+		}
+	}
+}
+
+func isShebang(src []byte, r *CommentRange) bool {
+	if r.StartRow != 0 {
+		return false
+	}
+	start := uint32(0)
+	if bytes.HasPrefix(src, bom) {
+		start = uint32(len(bom))
+	}
+	return r.StartByte == start && bytes.HasPrefix(src[start:], []byte("#!"))
+}
@@ -1,41 +1,81 @@
 package parser
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"sort"
 
 	sitter "github.com/smacker/go-tree-sitter"
 
 	"github.com/KashifKhn/remove-comments/cli/internal/languages"
 )
 
+type Protection uint8
+
+const (
+	Unprotected Protection = iota
+	ProtectMagic
+	ProtectDirective
+	ProtectSuppression
+)
+
+var languageRules = map[string]func(src []byte, nodes []*sitter.Node, ranges []CommentRange){
+	"go":         markGo,
+	"ruby":       markRuby,
+	"haskell":    markHaskell,
+	"dockerfile": markDockerfile,
+	"sql":        markSQL,
+}
+
+var commentFilters = map[string]func(src []byte, n *sitter.Node) bool{
+	"dockerfile": startsLine,
+}
+
+var sourceFixes = map[string]func(src []byte) []byte{
+	"groovy": groovySource,
+}
+
 type CommentRange struct {
 	StartRow    uint32
 	StartCol    uint32
 	EndRow      uint32
 	EndCol      uint32
+	StartByte   uint32
+	EndByte     uint32
 	IsFullLine  bool
 	IsMultiLine bool
+	Kind        Kind
+	Syntax      Kind
+	Protection  Protection
+	Markup      bool
+	Container   *CommentRange
+}
+
+func (r CommentRange) Text(src []byte) []byte {
+	if int(r.EndByte) > len(src) || r.StartByte > r.EndByte {
+		return nil
+	}
+	return src[r.StartByte:r.EndByte]
 }
 
 func Parse(src []byte, cfg languages.LangConfig) ([]CommentRange, error) {
-	lang := cfg.Language()
+	return parse(src, cfg, false)
+}
 
-	p := sitter.NewParser()
-	defer p.Close()
-	p.SetLanguage(lang)
+func parse(src []byte, cfg languages.LangConfig, injected bool) ([]CommentRange, error) {
+	src = lfOnly(src)
 
-	tree, err := p.ParseCtx(context.Background(), nil, src)
+	tree, err := Tree(src, cfg)
 	if err != nil {
-		return nil, fmt.Errorf("parse: %w", err)
+		return nil, err
 	}
 	defer tree.Close()
 
-	q, err := sitter.NewQuery([]byte(cfg.Query), lang)
+	q, err := cfg.CompileQuery()
 	if err != nil {
 		return nil, fmt.Errorf("query compile: %w", err)
 	}
-	defer q.Close()
 
 	lines := splitLines(src)
 
@@ -44,47 +84,224 @@ func Parse(src []byte, cfg languages.LangConfig) ([]CommentRange, error) {
 	qc.Exec(q, tree.RootNode())
 
 	var ranges []CommentRange
+	var nodes, containers []*sitter.Node
+	var end uint32
+	seen := map[uint32]int{}
 	for {
 		m, ok := qc.NextMatch()
 		if !ok {
 			break
 		}
+		m = qc.FilterPredicates(m, src)
 		for _, cap := range m.Captures {
 			n := cap.Node
-			sr := n.StartPoint().Row
-			sc := n.StartPoint().Column
-			er := n.EndPoint().Row
-			ec := n.EndPoint().Column
-
-			isMultiLine := er > sr
-			isFullLine := false
-			if !isMultiLine {
-				if int(sr) < len(lines) {
-					lineLen := uint32(len(lines[sr]))
-					isFullLine = sc == 0 && ec >= lineLen
+			name := q.CaptureNameForId(cap.Index)
+			if name == "container" {
+				containers = append(containers, n)
+				continue
+			}
+			if i, dup := seen[n.StartByte()]; dup {
+				if name != "comment" {
+					ranges[i].Kind = captureKind(name, ranges[i].Text(src))
 				}
+				continue
+			}
+			if n.StartByte() < end {
+				continue
+			}
+			if filter, ok := commentFilters[cfg.Name]; ok && !filter(src, n) {
+				continue
 			}
+			r := CommentRange{
+				StartRow:  n.StartPoint().Row,
+				StartCol:  n.StartPoint().Column,
+				EndRow:    n.EndPoint().Row,
+				EndCol:    n.EndPoint().Column,
+				StartByte: n.StartByte(),
+				EndByte:   n.EndByte(),
+			}
+			trimSpace(src, &r)
+			r.Kind = captureKind(name, r.Text(src))
+			r.Markup = cfg.Markup
+			setShape(src, lines, &r)
 
-			ranges = append(ranges, CommentRange{
-				StartRow:    sr,
-				StartCol:    sc,
-				EndRow:      er,
-				EndCol:      ec,
-				IsFullLine:  isFullLine,
-				IsMultiLine: isMultiLine,
-			})
+			end = n.EndByte()
+			seen[n.StartByte()] = len(ranges)
+			ranges = append(ranges, r)
+			nodes = append(nodes, n)
+		}
+	}
+
+	sort.Sort(byPosition{ranges, nodes})
+	for _, c := range containers {
+		widen(src, lines, ranges, c, seen)
+	}
+	if rules, ok := languageRules[cfg.Name]; ok {
+		rules(src, nodes, ranges)
+		for i := range ranges {
+			setShape(src, lines, &ranges[i])
 		}
 	}
+	if !injected {
+		markMagic(src, lines, ranges)
+	}
+	for i := range ranges {
+		if ranges[i].Protection == Unprotected && cfg.IsSuppression(ranges[i].Text(src)) {
+			ranges[i].Protection = ProtectSuppression
+			ranges[i].Kind = KindDirective
+		}
+	}
+	if !injected {
+		markLicense(src, cfg.Name, ranges)
+	}
 
+	injections := Injections(src, cfg.Name, tree.RootNode())
+	for _, inj := range injections {
+		sub, err := parse(src[inj.StartByte:inj.EndByte], inj.Lang, true)
+		if err != nil {
+			return nil, fmt.Errorf("%s block: %w", inj.Lang.Name, err)
+		}
+		for _, r := range sub {
+			r = inj.shift(r)
+			setShape(src, lines, &r)
+			if r.Container != nil {
+				c := inj.shift(*r.Container)
+				setShape(src, lines, &c)
+				r.Container = &c
+			}
+			ranges = append(ranges, r)
+		}
+	}
+	if len(injections) > 0 {
+		sort.Slice(ranges, func(i, j int) bool { return ranges[i].StartByte < ranges[j].StartByte })
+	}
 	return ranges, nil
 }
 
+func widen(src []byte, lines []string, ranges []CommentRange, c *sitter.Node, seen map[uint32]int) {
+	for i := 0; i < int(c.NamedChildCount()); i++ {
+		if _, ok := seen[c.NamedChild(i).StartByte()]; !ok {
+			return
+		}
+	}
+	var w *CommentRange
+	for i := range ranges {
+		r := &ranges[i]
+		if r.StartByte < c.StartByte() || r.EndByte > c.EndByte() {
+			continue
+		}
+		if w == nil {
+			w = &CommentRange{
+				StartRow:  c.StartPoint().Row,
+				StartCol:  c.StartPoint().Column,
+				EndRow:    c.EndPoint().Row,
+				EndCol:    c.EndPoint().Column,
+				StartByte: c.StartByte(),
+				EndByte:   c.EndByte(),
+				Kind:      r.Kind,
+				Markup:    true,
+			}
+			trimSpace(src, w)
+			setShape(src, lines, w)
+		}
+		r.Container = w
+	}
+}
+
+func setShape(src []byte, lines []string, r *CommentRange) {
+	r.IsMultiLine = r.EndRow > r.StartRow
+	r.IsFullLine = false
+	if r.IsMultiLine || int(r.StartRow) >= len(lines) {
+		return
+	}
+	lineLen := uint32(len(lines[r.StartRow]))
+	startCol := r.StartCol
+	if r.StartRow == 0 && bytes.HasPrefix(src, bom) {
+		startCol -= uint32(len(bom))
+	}
+	r.IsFullLine = startCol == 0 && r.EndCol >= lineLen
+}
+
+func Tree(src []byte, cfg languages.LangConfig) (*sitter.Tree, error) {
+	p := sitter.NewParser()
+	defer p.Close()
+	p.SetLanguage(cfg.Language())
+
+	src = lfOnly(src)
+	if fix, ok := sourceFixes[cfg.Name]; ok {
+		src = fix(src)
+	}
+	tree, err := p.ParseCtx(context.Background(), nil, src)
+	if err != nil {
+		return nil, fmt.Errorf("parse: %w", err)
+	}
+	return tree, nil
+}
+
+func lfOnly(src []byte) []byte {
+	var out []byte
+	for i, b := range src {
+		if b != '\r' || (i+1 < len(src) && src[i+1] == '\n') {
+			continue
+		}
+		if out == nil {
+			out = append([]byte(nil), src...)
+		}
+		out[i] = '\n'
+	}
+	if out == nil {
+		return src
+	}
+	return out
+}
+
+type byPosition struct {
+	ranges []CommentRange
+	nodes  []*sitter.Node
+}
+
+func (b byPosition) Len() int { return len(b.ranges) }
+
+func (b byPosition) Less(i, j int) bool { return b.ranges[i].StartByte < b.ranges[j].StartByte }
+
+func (b byPosition) Swap(i, j int) {
+	b.ranges[i], b.ranges[j] = b.ranges[j], b.ranges[i]
+	b.nodes[i], b.nodes[j] = b.nodes[j], b.nodes[i]
+}
+
+func trimSpace(src []byte, r *CommentRange) {
+	for r.StartByte < r.EndByte && isSpace(src[r.StartByte]) {
+		if src[r.StartByte] == '\n' {
+			r.StartRow++
+			r.StartCol = 0
+		} else {
+			r.StartCol++
+		}
+		r.StartByte++
+	}
+	end := r.EndByte
+	for end > r.StartByte && isSpace(src[end-1]) {
+		end--
+	}
+	if end == r.EndByte {
+		return
+	}
+	r.EndByte = end
+	r.EndRow = r.StartRow + uint32(bytes.Count(src[r.StartByte:end], []byte("\n")))
+	lineStart := bytes.LastIndexByte(src[:end], '\n') + 1
+	r.EndCol = end - uint32(lineStart)
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r' || b == '\f'
+}
+
 func splitLines(src []byte) []string {
 	var lines []string
 	start := 0
 	for i, b := range src {
 		if b == '\n' {
-			lines = append(lines, string(src[start:i]))
+			lines = append(lines, string(bytes.TrimSuffix(src[start:i], []byte("\r"))))
 			start = i + 1
 		}
 	}
@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/KashifKhn/remove-comments/cli/internal/languages"
@@ -221,6 +222,23 @@ func TestParse_Rust_LineComment(t *testing.T) {
 	}
 }
 
+func TestParse_Rust_NestedBlockComment(t *testing.T) {
+	src := []byte("fn main() {\n    let x = 1; /* outer /* inner */ still outer */\n    /*\n     * /* nested */\n     */\n}\n")
+	ranges, err := Parse(src, langFor(".rs", t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ranges) != 2 {
+		t.Fatalf("expected 2 comments, got %d", len(ranges))
+	}
+	if got := string(ranges[0].Text(src)); got != "/* outer /* inner */ still outer */" {
+		t.Errorf("nested comment text = %q", got)
+	}
+	if !ranges[1].IsMultiLine || ranges[1].Kind != KindBlock {
+		t.Errorf("expected multi-line block comment, got %+v", ranges[1])
+	}
+}
+
 func TestParse_Lua_Comment(t *testing.T) {
 	src := []byte("local x = 1\n-- lua comment\nlocal y = 2\n")
 	ranges, err := Parse(src, langFor(".lua", t))
@@ -408,3 +426,574 @@ func TestSplitLines_BasicNewlines(t *testing.T) {
 		})
 	}
 }
+
+func TestParse_ByteOffsets_MatchCommentText(t *testing.T) {
+	src := []byte("package main\n\nfunc f() {} // TODO: tidy\n")
+	ranges, err := Parse(src, langFor(".go", t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ranges) != 1 {
+		t.Fatalf("expected 1 comment, got %d", len(ranges))
+	}
+	if got := string(ranges[0].Text(src)); got != "// TODO: tidy" {
+		t.Errorf("got text %q, want %q", got, "// TODO: tidy")
+	}
+}
+
+func protectedTexts(src []byte, ranges []CommentRange) []string {
+	var out []string
+	for _, r := range ranges {
+		if r.Protection != Unprotected {
+			out = append(out, string(r.Text(src)))
+		}
+	}
+	return out
+}
+
+func TestParse_MagicComments(t *testing.T) {
+	tests := []struct {
+		name string
+		ext  string
+		src  string
+		want []string
+	}{
+		{"bash shebang", ".sh", "#!/usr/bin/env bash\n# plain\necho hi\n", []string{"#!/usr/bin/env bash"}},
+		{"python shebang and coding", ".py", "#!/usr/bin/env python\n# -*- coding: utf-8 -*-\n# plain\nx = 1\n",
+			[]string{"#!/usr/bin/env python", "# -*- coding: utf-8 -*-"}},
+		{"python pep263 plain form", ".py", "# vim: set fileencoding=latin-1 :\nx = 1\n", []string{"# vim: set fileencoding=latin-1 :"}},
+		{"coding below line two is ordinary", ".py", "x = 1\ny = 2\n# coding: utf-8\n", nil},
+		{"shebang not on first line", ".sh", "echo hi\n#!/bin/sh\n", nil},
+		{"vim modeline at bottom", ".lua", "local x = 1\n-- vim: ts=2 sw=2 et\n", []string{"-- vim: ts=2 sw=2 et"}},
+		{"vim modeline in middle", ".sh", "a\nb\nc\nd\ne\nf\n# vim: ts=2\ng\nh\ni\nj\nk\nl\n", nil},
+		{"emacs first line", ".sh", "# -*- mode: sh -*-\necho hi\n", []string{"# -*- mode: sh -*-"}},
+		{"emacs local variables", ".py", "x = 1\n# Local Variables:\n# indent-tabs-mode: nil\n# End:\n", []string{"# Local Variables:", "# indent-tabs-mode: nil", "# End:"}},
+		{"ordinary comment", ".py", "# just a note\nx = 1\n", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := []byte(tt.src)
+			ranges, err := Parse(src, langFor(tt.ext, t))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := protectedTexts(src, ranges)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("protected %d: got %q, want %q", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParse_Lua_CommentAfterCode_StartsOnOwnLine(t *testing.T) {
+	src := []byte("local x = 1\n-- lua comment\nlocal y = 2\n")
+	ranges, err := Parse(src, langFor(".lua", t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ranges) != 1 {
+		t.Fatalf("expected 1 comment, got %d", len(ranges))
+	}
+	r := ranges[0]
+	if r.StartRow != 1 || r.StartCol != 0 {
+		t.Errorf("expected comment to start at 1:0, got %d:%d", r.StartRow, r.StartCol)
+	}
+	if !r.IsFullLine || r.IsMultiLine {
+		t.Errorf("expected full-line single-line comment, got %+v", r)
+	}
+	if got := string(r.Text(src)); got != "-- lua comment" {
+		t.Errorf("got text %q", got)
+	}
+}
+
+func TestParse_Go_Directives(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		{
+			name: "build constraints before package",
+			src:  "//go:build linux\n// +build linux\n\n// Package main doc.\npackage main\n",
+			want: []string{"//go:build linux", "// +build linux"},
+		},
+		{
+			name: "build constraint after package is ordinary",
+			src:  "package main\n\n//go:build linux\nfunc f() {}\n",
+			want: nil,
+		},
+		{
+			name: "generate and linkname",
+			src:  "package main\n\n//go:generate stringer -type=T\n//go:linkname now runtime.now\nfunc now() int64\n",
+			want: []string{"//go:generate stringer -type=T", "//go:linkname now runtime.now"},
+		},
+		{
+			name: "embed on var and in var block",
+			src:  "package main\n\nimport \"embed\"\n\n//go:embed static/*\nvar fs embed.FS\n\nvar (\n\t//go:embed a.txt\n\ta string\n)\n",
+			want: []string{"//go:embed static/*", "//go:embed a.txt"},
+		},
+		{
+			name: "embed not followed by var",
+			src:  "package main\n\n//go:embed a.txt\nfunc f() {}\n",
+			want: nil,
+		},
+		{
+			name: "export before func",
+			src:  "package main\n\nimport \"C\"\n\n//export Foo\nfunc Foo() {}\n",
+			want: []string{"//export Foo"},
+		},
+		{
+			name: "line directives",
+			src:  "package main\n\n//line gen.y:10\nfunc f() { /*line gen.y:12*/ }\n",
+			want: []string{"//line gen.y:10", "/*line gen.y:12*/"},
+		},
+		{
+			name: "cgo preamble",
+			src:  "package main\n\n// ordinary\n\n// #cgo LDFLAGS: -lm\n/*\n#include <math.h>\n*/\nimport \"C\"\n",
+			want: []string{"// #cgo LDFLAGS: -lm", "/*\n#include <math.h>\n*/"},
+		},
+		{
+			name: "comment before other import",
+			src:  "package main\n\n// fmt is used\nimport \"fmt\"\n",
+			want: nil,
+		},
+		{
+			name: "example output",
+			src:  "package main\n\nfunc ExampleHello() {\n\t// call it\n\thello()\n\t// Output:\n\t// hello\n}\n\nfunc Hello() {\n\t// Output: not an example\n}\n",
+			want: []string{"// Output:", "// hello"},
+		},
+		{
+			name: "ordinary comments",
+			src:  "package main\n\n// go: not a directive\n// Foo does things.\nfunc Foo() {}\n",
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := []byte(tt.src)
+			ranges, err := Parse(src, langFor(".go", t))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := protectedTexts(src, ranges)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			for _, r := range ranges {
+				if r.Protection != Unprotected && r.Protection != ProtectDirective {
+					t.Errorf("unexpected protection %v for %q", r.Protection, r.Text(src))
+				}
+			}
+		})
+	}
+}
+
+func TestParse_HaskellPragmasProtected(t *testing.T) {
+	src := []byte("{-# LANGUAGE OverloadedStrings #-}\n{-# OPTIONS_GHC -Wall #-}\n-- | Doc.\nmodule A where\n\n-- c\nf :: Int -> Int\nf = id\n{-# INLINE f #-}\n")
+	ranges, err := Parse(src, langFor(".hs", t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"{-# LANGUAGE OverloadedStrings #-}", "{-# OPTIONS_GHC -Wall #-}", "{-# INLINE f #-}"}
+	if got := protectedTexts(src, ranges); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	for _, r := range ranges {
+		if r.Protection != Unprotected && (r.Protection != ProtectDirective || r.Kind != KindDirective) {
+			t.Errorf("%q: protection %v kind %v", r.Text(src), r.Protection, r.Kind)
+		}
+	}
+}
+
+func TestParse_SuppressionComments(t *testing.T) {
+	tests := []struct {
+		name string
+		ext  string
+		src  string
+		want []string
+	}{
+		{"eslint", ".js", "// eslint-disable-next-line no-console\nconsole.log(1); // note\n", []string{"// eslint-disable-next-line no-console"}},
+		{"ts expect error", ".ts", "// @ts-expect-error\nconst x: number = 'a';\n", []string{"// @ts-expect-error"}},
+		{"python noqa and type ignore", ".py", "import os  # noqa: F401\nx = f()  # type: ignore[attr]\n# pylint: disable=invalid-name\n# plain\n",
+			[]string{"# noqa: F401", "# type: ignore[attr]", "# pylint: disable=invalid-name"}},
+		{"go nolint", ".go", "package main\n\nfunc f() {} //nolint:errcheck\n// nolint:gocyclo\nfunc g() {}\n", []string{"//nolint:errcheck", "// nolint:gocyclo"}},
+		{"dart ignore", ".dart", "// ignore: unused_element\nvoid _f() {}\n", []string{"// ignore: unused_element"}},
+		{"shellcheck", ".sh", "# shellcheck disable=SC2086\necho $x\n", []string{"# shellcheck disable=SC2086"}},
+		{"clang-tidy", ".cpp", "int x; // NOLINT\n// NOLINTNEXTLINE(bugprone-*)\nint y;\n", []string{"// NOLINT", "// NOLINTNEXTLINE(bugprone-*)"}},
+		{"hlint and ormolu", ".hs", "{- HLINT ignore \"Use map\" -}\n{- ORMOLU_DISABLE -}\nx = 1\n{- ORMOLU_ENABLE -}\n-- hlint is fine\n",
+			[]string{"{- HLINT ignore \"Use map\" -}", "{- ORMOLU_DISABLE -}", "{- ORMOLU_ENABLE -}"}},
+		{"ordinary", ".js", "// ignore this helper\nlet x;\n", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := []byte(tt.src)
+			ranges, err := Parse(src, langFor(tt.ext, t))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, r := range ranges {
+				if r.Protection == ProtectSuppression {
+					got = append(got, string(r.Text(src)))
+				}
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func kindsOf(src []byte, ranges []CommentRange) []string {
+	var out []string
+	for _, r := range ranges {
+		out = append(out, r.Kind.String()+":"+string(r.Text(src)))
+	}
+	return out
+}
+
+func TestParse_Kinds(t *testing.T) {
+	tests := []struct {
+		name string
+		ext  string
+		src  string
+		want []string
+	}{
+		{"js line block doc", ".js", "// a\n/* b */\n/** c */\n/**/\nfunction f() {}\n",
+			[]string{"line:// a", "block:/* b */", "doc:/** c */", "block:/**/"}},
+		{"java doc", ".java", "class A {\n/** doc */\nvoid f() {}\n// x\n}\n",
+			[]string{"doc:/** doc */", "line:// x"}},
+		{"c doxygen", ".c", "/// brief\n//! also\n//// banner\n/*! qt */\nint x;\n",
+			[]string{"doc:/// brief", "doc://! also", "line://// banner", "doc:/*! qt */"}},
+		{"rust doc", ".rs", "/// outer\n//! inner\n// plain\n//// not doc\nfn main() {}\n",
+			[]string{"doc:/// outer", "doc://! inner", "line:// plain", "line://// not doc"}},
+		{"rust block doc", ".rs", "/** outer */\n/*! inner */\n/* plain */\n/**/\n/*** banner */\nfn main() {}\n",
+			[]string{"doc:/** outer */", "doc:/*! inner */", "block:/* plain */", "block:/**/", "block:/*** banner */"}},
+		{"lua annotations", ".lua", "local x = 1\n---@param y number\n-- plain\n--[[ block ]]\n",
+			[]string{"doc:---@param y number", "line:-- plain", "block:--[[ block ]]"}},
+		{"go doc attached", ".go", "package main\n\n// detached\n\n// Foo does it.\n// More.\nfunc Foo() {\n\t// inside\n}\n",
+			[]string{"line:// detached", "doc:// Foo does it.", "doc:// More.", "line:// inside"}},
+		{"go package doc and directive", ".go", "//go:build linux\n\n// Package main is it.\npackage main\n",
+			[]string{"directive://go:build linux", "doc:// Package main is it."}},
+		{"shebang and suppression", ".sh", "#!/bin/sh\n# shellcheck disable=SC2086\necho $x\n",
+			[]string{"shebang:#!/bin/sh", "directive:# shellcheck disable=SC2086"}},
+		{"python coding", ".py", "# -*- coding: utf-8 -*-\nx = 1\n", []string{"directive:# -*- coding: utf-8 -*-"}},
+		{"html", ".html", "<!-- c -->\n<p></p>\n", []string{"block:<!-- c -->"}},
+		{"ruby", ".rb", "# frozen_string_literal: true\n# note\n=begin\nblock\n=end\nx = 1 # rubocop:disable Style/X\n# frozen_string_literal: false\n",
+			[]string{"directive:# frozen_string_literal: true", "line:# note", "block:=begin\nblock\n=end", "directive:# rubocop:disable Style/X", "line:# frozen_string_literal: false"}},
+		{"php", ".php", "<p>hi</p>\n<?php\n// a\n# b\n/* c */\n/** d */\nfunction f() {} // e ?>\n<!-- html -->\n",
+			[]string{"line:// a", "line:# b", "block:/* c */", "doc:/** d */", "line:// e", "block:<!-- html -->"}},
+		{"php html", ".php", "<!-- top -->\n<div>\n<?= $x ?><!-- y --></div>\n<?php if ($x): ?>\n  <!-- z -->\n<?php endif; ?>\n<script>// s\n</script>\n",
+			[]string{"block:<!-- top -->", "block:<!-- y -->", "block:<!-- z -->", "line:// s"}},
+		{"kotlin kdoc", ".kt", "/** KDoc */\n// line\n/* a /* nested */ b */\nfun f() = 1\n",
+			[]string{"doc:/** KDoc */", "line:// line", "block:/* a /* nested */ b */"}},
+		{"scala nested", ".scala", "/** ScalaDoc */\n/* a /* n */ b */\n// c\nobject A\n",
+			[]string{"doc:/** ScalaDoc */", "block:/* a /* n */ b */", "line:// c"}},
+		{"groovy", ".gradle", "#!/usr/bin/env groovy\n/**\n * Doc.\n */\ndef f() { 1 }\n/* b */ // c\n",
+			[]string{"shebang:#!/usr/bin/env groovy", "doc:/**\n * Doc.\n */", "block:/* b */", "line:// c"}},
+		{"swift markup", ".swift", "/// Doc\n//// banner\n/** Block */\n/* a /* n */ b */\nlet x = 1 // swiftlint:disable:this x\n",
+			[]string{"doc:/// Doc", "line://// banner", "doc:/** Block */", "block:/* a /* n */ b */", "directive:// swiftlint:disable:this x"}},
+		{"csharp xml doc", ".cs", "// <auto-generated />\n/// <summary>X</summary>\n/* b */\nclass A {}\n",
+			[]string{"directive:// <auto-generated />", "doc:/// <summary>X</summary>", "block:/* b */"}},
+		{"dockerfile directives", ".dockerfile", "# syntax=docker/dockerfile:1\n# escape=`\n# stage\nFROM alpine AS base # arg\nRUN echo a \\\n  # dropped by docker\n  && echo b\n# check=skip=all\n",
+			[]string{"directive:# syntax=docker/dockerfile:1", "directive:# escape=`", "line:# stage", "line:# dropped by docker", "line:# check=skip=all"}},
+		{"dockerfile directive after comment", ".dockerfile", "# note\n# syntax=docker/dockerfile:1\nFROM alpine\n",
+			[]string{"line:# note", "line:# syntax=docker/dockerfile:1"}},
+		{"hcl", ".tf", "# a\n// b\n/* c */\nx = 1 # tfsec:ignore:aws-x\n",
+			[]string{"line:# a", "line:// b", "block:/* c */", "directive:# tfsec:ignore:aws-x"}},
+		{"protobuf", ".proto", "syntax = \"proto3\";\n// a\n/* b */\nmessage A {} // buf:lint:ignore X\n",
+			[]string{"line:// a", "block:/* b */", "directive:// buf:lint:ignore X"}},
+		{"sql directives", ".sql", "-- +goose Up\n-- name: GetUser :one\nSELECT /*+ INDEX(u) */ * FROM u; /*!40101 SET x=1 */\n-- plain\n/* block */\n",
+			[]string{"directive:-- +goose Up", "directive:-- name: GetUser :one", "directive:/*+ INDEX(u) */", "directive:/*!40101 SET x=1 */", "line:-- plain", "block:/* block */"}},
+		{"cue", ".cue", "// a\npackage a\nx: 1 // b\n", []string{"line:// a", "line:// b"}},
+		{"ocaml odoc", ".ml", "(** doc *)\n(* a (* nested *) b *)\n(**)\n(*** banner *)\nlet x = 1\n",
+			[]string{"doc:(** doc *)", "block:(* a (* nested *) b *)", "block:(**)", "block:(*** banner *)"}},
+		{"elm doc", ".elm", "module A exposing (x)\n\n{-| Doc -}\n{- a {- n -} b -}\nx = 1 -- t\n",
+			[]string{"doc:{-| Doc -}", "block:{- a {- n -} b -}", "line:-- t"}},
+		{"haskell haddock", ".hs", "{-# LANGUAGE GADTs #-}\n-- | Module doc.\nmodule A where\n\n-- plain\n{- a {- n -} b -}\nf :: Int -> Int -- ^ arg\nf x = x {- i -}\n{-| g -}\ng = 1\n",
+			[]string{"directive:{-# LANGUAGE GADTs #-}", "doc:-- | Module doc.", "line:-- plain", "block:{- a {- n -} b -}", "doc:-- ^ arg", "block:{- i -}", "doc:{-| g -}"}},
+		{"elixir docs", ".ex", "defmodule A do\n  @moduledoc \"\"\"\n  Mod.\n  \"\"\"\n  # c\n  @doc \"f\"\n  def f, do: 1\n  @typedoc \"t\"\n  @type t :: integer\n  @docs_url \"x\"\n  @doc false\n  def g, do: 2\nend\n",
+			[]string{"doc:@moduledoc \"\"\"\n  Mod.\n  \"\"\"", "line:# c", "doc:@doc \"f\"", "doc:@typedoc \"t\""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := []byte(tt.src)
+			ranges, err := Parse(src, langFor(tt.ext, t))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := kindsOf(src, ranges)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse_LicenseKind(t *testing.T) {
+	tests := []struct {
+		name string
+		ext  string
+		src  string
+		want []string
+	}{
+		{"spdx header kept apart from package doc", ".go",
+			"// Copyright 2024 Acme\n// SPDX-License-Identifier: MIT\n\n// Package x does y.\npackage x\n",
+			[]string{"// Copyright 2024 Acme", "// SPDX-License-Identifier: MIT"}},
+		{"block header", ".c", "/*\n * Copyright (C) 2020 Foo\n */\n\nint x; // copyright later\n",
+			[]string{"/*\n * Copyright (C) 2020 Foo\n */"}},
+		{"shebang then license", ".sh", "#!/bin/sh\n# Copyright 2021 Foo\n#\n# Licensed under MIT\n\n# setup\necho hi\n",
+			[]string{"# Copyright 2021 Foo", "#", "# Licensed under MIT"}},
+		{"important comment in css", ".css", "/*! normalize v8 */\n/* reset */\nbody {}\n",
+			[]string{"/*! normalize v8 */"}},
+		{"only the matching run", ".go", "// Package banner.\n// Copyright 2024 Acme\n// All rights reserved.\n/* unrelated */\n\n// Another paragraph.\npackage x\n",
+			[]string{"// Package banner.", "// Copyright 2024 Acme", "// All rights reserved."}},
+		{"blank line ends the run", ".py", "# Copyright 2021 Foo\n\n# setup notes\nx = 1\n",
+			[]string{"# Copyright 2021 Foo"}},
+		{"important marker ignored outside css and js", ".c", "/*! doxygen */\nint x;\n", nil},
+		{"header must lead the file", ".go", "package x\n\n// Copyright 2024 Acme\n", nil},
+		{"utf8 bom before header", ".js", "\xef\xbb\xbf// SPDX-License-Identifier: Apache-2.0\nlet x;\n",
+			[]string{"// SPDX-License-Identifier: Apache-2.0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := []byte(tt.src)
+			ranges, err := Parse(src, langFor(tt.ext, t))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, r := range ranges {
+				if r.Kind == KindLicense {
+					got = append(got, string(r.Text(src)))
+					if want := shapeKind(r.Text(src)); r.Syntax != want {
+						t.Errorf("%q: syntax kind %v, want %v", r.Text(src), r.Syntax, want)
+					}
+				}
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseKinds(t *testing.T) {
+	got, err := ParseKinds([]string{"doc", " Line "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got[KindDoc] || !got[KindLine] || len(got) != 2 {
+		t.Errorf("unexpected kinds: %v", got)
+	}
+	if _, err := ParseKinds([]string{"docs"}); err == nil {
+		t.Error("expected error for unknown kind")
+	}
+	if got, err := ParseKinds(nil); err != nil || got != nil {
+		t.Errorf("expected nil set for no kinds, got %v, %v", got, err)
+	}
+}
+
+func TestParse_Dart_DocKind(t *testing.T) {
+	src := []byte("/// doc comment\n// plain\nvoid main() {}\n")
+	ranges, err := Parse(src, langFor(".dart", t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := kindsOf(src, ranges)
+	want := []string{"doc:/// doc comment", "line:// plain"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestParse_LineEndingsAndBOM(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+	}{
+		{"lf", "x = 1\n# full\ny = 2  # inline\n"},
+		{"crlf", "x = 1\r\n# full\r\ny = 2  # inline\r\n"},
+		{"cr", "x = 1\r# full\ry = 2  # inline\r"},
+		{"mixed", "x = 1\r\n# full\ry = 2  # inline\n"},
+		{"bom crlf", "\xef\xbb\xbfx = 1\r\n# full\r\ny = 2  # inline\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := []byte(tt.src)
+			ranges, err := Parse(src, langFor(".py", t))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(ranges) != 2 {
+				t.Fatalf("expected 2 comments, got %d", len(ranges))
+			}
+			full, inline := ranges[0], ranges[1]
+			if full.StartRow != 1 || !full.IsFullLine || string(full.Text(src)) != "# full" {
+				t.Errorf("full-line comment: row=%d full=%v text=%q", full.StartRow, full.IsFullLine, full.Text(src))
+			}
+			if inline.StartRow != 2 || inline.IsFullLine || string(inline.Text(src)) != "# inline" {
+				t.Errorf("inline comment: row=%d full=%v text=%q", inline.StartRow, inline.IsFullLine, inline.Text(src))
+			}
+		})
+	}
+}
+
+func TestParse_BOM_FullLineOnFirstRow(t *testing.T) {
+	src := []byte("\xef\xbb\xbf// first\r\npackage p\r\n")
+	ranges, err := Parse(src, langFor(".go", t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ranges) != 1 || !ranges[0].IsFullLine {
+		t.Errorf("expected one full-line comment, got %+v", ranges)
+	}
+}
+
+func TestParse_HTML_Injection(t *testing.T) {
+	src := []byte("<!-- page -->\n<script>\n  // setup\n  let x = 1; /* one */\n</script>\n<style>/** theme */ a { color: red; }</style>\n<script type=\"text/template\"><!-- kept --></script>\n<script type=module>/*! banner */</script>\n")
+	ranges, err := Parse(src, langFor(".html", t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		text     string
+		row, col uint32
+		full     bool
+		markup   bool
+	}{
+		{"<!-- page -->", 0, 0, true, true},
+		{"// setup", 2, 2, false, false},
+		{"/* one */", 3, 13, false, false},
+		{"/** theme */", 5, 7, false, false},
+		{"/*! banner */", 7, 20, false, false},
+	}
+	if len(ranges) != len(want) {
+		t.Fatalf("expected %d comments, got %d: %q", len(want), len(ranges), kindsOf(src, ranges))
+	}
+	for i, w := range want {
+		r := ranges[i]
+		if got := string(r.Text(src)); got != w.text {
+			t.Errorf("range %d: text %q, want %q", i, got, w.text)
+		}
+		if r.StartRow != w.row || r.StartCol != w.col {
+			t.Errorf("range %d: at %d:%d, want %d:%d", i, r.StartRow, r.StartCol, w.row, w.col)
+		}
+		if r.IsFullLine != w.full || r.Markup != w.markup {
+			t.Errorf("range %d: full=%v markup=%v, want %v/%v", i, r.IsFullLine, r.Markup, w.full, w.markup)
+		}
+	}
+}
+
+func TestParse_InjectionSkipsFileStartRules(t *testing.T) {
+	src := []byte("<!-- Copyright 2024 Acme -->\n<p>hi</p>\n<script>\n/* Copyright 2020 Vendor */\n// vim: set ts=2:\nlet x = 1;\n</script>\n<style>/*! reset */ a {}</style>\n")
+	ranges, err := Parse(src, langFor(".html", t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := kindsOf(src, ranges)
+	want := []string{"license:<!-- Copyright 2024 Acme -->", "block:/* Copyright 2020 Vendor */", "line:// vim: set ts=2:", "block:/*! reset */"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	for _, r := range ranges {
+		if r.Protection != Unprotected {
+			t.Errorf("%q: unexpected protection %v", r.Text(src), r.Protection)
+		}
+	}
+}
+
+func TestParse_SingleFileComponents(t *testing.T) {
+	tests := []struct {
+		name string
+		ext  string
+		src  string
+		want []string
+	}{
+		{"vue", ".vue",
+			"<template>\n  <!-- note -->\n  <div>{{ x }}</div>\n</template>\n<script setup lang=\"ts\">\n// c\nconst x: number = 1 /* one */\n</script>\n<style scoped lang=\"scss\">\n// line\n.a { .b { color: red; } }\n</style>\n",
+			[]string{"block:<!-- note -->", "line:// c", "block:/* one */", "line:// line"}},
+		{"vue options api", ".vue",
+			"<script>\n/** docs */\nexport default {}\n</script>\n<style lang=\"less\">\n// untouched\n</style>\n",
+			[]string{"doc:/** docs */"}},
+		{"svelte", ".svelte",
+			"<script lang=\"ts\">\n  // s\n  let x: number = 1;\n</script>\n\n<!-- svelte-ignore a11y-autofocus -->\n<!-- tmpl -->\n<div>{x}</div>\n\n<style>\n  /* st */\n  a { color: red; }\n</style>\n",
+			[]string{"line:// s", "directive:<!-- svelte-ignore a11y-autofocus -->", "block:<!-- tmpl -->", "block:/* st */"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := []byte(tt.src)
+			ranges, err := Parse(src, langFor(tt.ext, t))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := kindsOf(src, ranges)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse_JSX_CommentContainers(t *testing.T) {
+	tests := []struct {
+		ext       string
+		src       string
+		container string
+		full      bool
+	}{
+		{".jsx", "<div>\n{/* note */}\n</div>;\n", "{/* note */}", true},
+		{".js", "<p>x{/** y */}z</p>;\n", "{/** y */}", false},
+		{".tsx", "<p>{\n  // why\n}</p>;\n", "{\n  // why\n}", false},
+		{".tsx", "<p>{x /* keep x */}</p>;\n", "", false},
+		{".jsx", "<p>{/* a */ /* b */}</p>;\n", "{/* a */ /* b */}", false},
+		{".jsx", "<p>{/* a */ x /* b */}</p>;\n", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ext+" "+tt.src, func(t *testing.T) {
+			src := []byte(tt.src)
+			ranges, err := Parse(src, langFor(tt.ext, t))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(ranges) == 0 {
+				t.Fatal("expected a comment")
+			}
+			for _, r := range ranges {
+				if r.Container == nil {
+					if tt.container != "" {
+						t.Errorf("%q: no container, want %q", r.Text(src), tt.container)
+					}
+					continue
+				}
+				c := r.Container
+				if got := string(c.Text(src)); got != tt.container {
+					t.Errorf("%q: container %q, want %q", r.Text(src), got, tt.container)
+				}
+				if c.IsFullLine != tt.full || !c.Markup {
+					t.Errorf("full=%v markup=%v, want %v/true", c.IsFullLine, c.Markup, tt.full)
+				}
+			}
+		})
+	}
+}
+
+func TestParse_Groovy_OneLineDocStopsAtTerminator(t *testing.T) {
+	src := []byte("/** a */\ndef f() { 1 }\n/* b */\ndef g() { \"/** c */\" }\n")
+	ranges, err := Parse(src, langFor(".groovy", t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"doc:/** a */", "block:/* b */"}
+	if got := kindsOf(src, ranges); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	if r := ranges[0]; r.EndRow != 0 || r.EndCol != 8 || !r.IsFullLine {
+		t.Errorf("end %d:%d full=%v, want 0:8 full", r.EndRow, r.EndCol, r.IsFullLine)
+	}
+}
@@ -0,0 +1,30 @@
+package parser
+
+import (
+	"regexp"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+var rubyMagicComment = regexp.MustCompile(`(?i)^#.*\b(frozen[-_]string[-_]literal|shareable[-_]constant[-_]value|warn[-_]indent|warn[-_]past[-_]scope)\s*:`)
+
+func markRuby(src []byte, nodes []*sitter.Node, ranges []CommentRange) {
+	for i, n := range nodes {
+		if !precedesCode(n) {
+			break
+		}
+		if rubyMagicComment.Match(ranges[i].Text(src)) {
+			ranges[i].Protection = ProtectMagic
+			ranges[i].Kind = KindDirective
+		}
+	}
+}
+
+func precedesCode(n *sitter.Node) bool {
+	for prev := n.PrevNamedSibling(); prev != nil; prev = prev.PrevNamedSibling() {
+		if prev.Type() != "comment" {
+			return false
+		}
+	}
+	return n.Parent() != nil && n.Parent().Parent() == nil
+}
@@ -0,0 +1,18 @@
+package parser
+
+import (
+	"regexp"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+var sqlDirective = regexp.MustCompile(`^(/\*[+!]|--\s*\+(goose|migrate)\s|--\s*name:\s*\w+\s+:\w+)`)
+
+func markSQL(src []byte, nodes []*sitter.Node, ranges []CommentRange) {
+	for i := range nodes {
+		if sqlDirective.Match(ranges[i].Text(src)) {
+			ranges[i].Protection = ProtectDirective
+			ranges[i].Kind = KindDirective
+		}
+	}
+}
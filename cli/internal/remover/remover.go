@@ -1,30 +1,140 @@
 package remover
 
 import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
 	"github.com/KashifKhn/remove-comments/cli/internal/parser"
 )
 
-type inlineEdit struct {
-	row      uint32
-	startCol uint32
-	endCol   uint32
+const toLineEnd = ^uint32(0)
+
+var bom = []byte("\xef\xbb\xbf")
+
+type span struct {
+	start, end uint32
+	markup     bool
+	indent     []byte
+}
+
+type BlankPolicy uint8
+
+const (
+	BlanksPreserve BlankPolicy = iota
+	BlanksCollapse
+	BlanksTrim
+)
+
+func ParseBlankPolicy(s string) (BlankPolicy, error) {
+	switch s {
+	case "", "preserve":
+		return BlanksPreserve, nil
+	case "collapse":
+		return BlanksCollapse, nil
+	case "trim":
+		return BlanksTrim, nil
+	}
+	return BlanksPreserve, fmt.Errorf("invalid blank-line policy %q (want preserve, collapse or trim)", s)
+}
+
+type Options struct {
+	Blanks          BlankPolicy
+	MaxBlank        int
+	PreserveLines   bool
+	PreserveColumns bool
+}
+
+func (o Options) keepsLines() bool {
+	return o.PreserveLines || o.PreserveColumns
+}
+
+type Stats struct {
+	BlankLines   int
+	ClearedLines int
 }
 
 func Remove(src []byte, ranges []parser.CommentRange) []byte {
+	out, _ := RemoveWith(src, ranges, Options{})
+	return out
+}
+
+func RemoveWith(src []byte, ranges []parser.CommentRange, opts Options) ([]byte, Stats) {
 	if len(ranges) == 0 {
-		return src
+		return src, Stats{}
 	}
 
+	if opts.keepsLines() {
+		opts.Blanks = BlanksPreserve
+	}
+	ranges = containers(src, ranges)
+	var prefix []byte
+	if bytes.HasPrefix(src, bom) {
+		prefix, src = src[:len(bom)], src[len(bom):]
+		ranges = shiftFirstRow(ranges, uint32(len(bom)))
+	}
 	lines := splitLines(src)
-	out := processLines(lines, ranges)
-	return joinLines(out)
+	out, stats := processLines(lines, ranges, opts)
+	return append(prefix[:len(prefix):len(prefix)], joinLines(out)...), stats
+}
+
+func containers(src []byte, ranges []parser.CommentRange) []parser.CommentRange {
+	rest := map[uint32][]byte{}
+	for _, r := range ranges {
+		c := r.Container
+		if c == nil || int(c.EndByte) > len(src) {
+			continue
+		}
+		text, ok := rest[c.StartByte]
+		if !ok {
+			text = append([]byte(nil), src[c.StartByte:c.EndByte]...)
+			rest[c.StartByte] = text
+		}
+		for i := r.StartByte; i < r.EndByte; i++ {
+			text[i-c.StartByte] = ' '
+		}
+	}
+	if len(rest) == 0 {
+		return ranges
+	}
+
+	out := make([]parser.CommentRange, 0, len(ranges))
+	done := map[uint32]bool{}
+	for _, r := range ranges {
+		c := r.Container
+		if c == nil || int(c.EndByte) > len(src) || len(bytes.Trim(rest[c.StartByte], "{} \t\n")) > 0 {
+			out = append(out, r)
+			continue
+		}
+		if !done[c.StartByte] {
+			done[c.StartByte] = true
+			out = append(out, *c)
+		}
+	}
+	return out
+}
+
+func shiftFirstRow(ranges []parser.CommentRange, n uint32) []parser.CommentRange {
+	shifted := make([]parser.CommentRange, len(ranges))
+	copy(shifted, ranges)
+	for i := range shifted {
+		r := &shifted[i]
+		if r.StartRow == 0 {
+			r.StartCol -= min(r.StartCol, n)
+		}
+		if r.EndRow == 0 {
+			r.EndCol -= min(r.EndCol, n)
+		}
+	}
+	return shifted
 }
 
 func splitLines(src []byte) [][]byte {
 	var lines [][]byte
 	start := 0
 	for i, b := range src {
-		if b == '\n' {
+		if b == '\n' || (b == '\r' && (i+1 == len(src) || src[i+1] != '\n')) {
 			lines = append(lines, src[start:i+1])
 			start = i + 1
 		}
@@ -49,85 +159,181 @@ func joinLines(lines [][]byte) []byte {
 	return out
 }
 
-func processLines(lines [][]byte, ranges []parser.CommentRange) [][]byte {
-	delRows := map[uint32]bool{}
-	var inlineEdits []inlineEdit
-
+func processLines(lines [][]byte, ranges []parser.CommentRange, opts Options) ([][]byte, Stats) {
+	spans := map[uint32][]span{}
 	for _, r := range ranges {
-		if r.IsMultiLine {
-			for row := r.StartRow; row <= r.EndRow; row++ {
-				delRows[row] = true
-			}
+		if r.StartRow == r.EndRow {
+			spans[r.StartRow] = append(spans[r.StartRow], span{start: r.StartCol, end: r.EndCol, markup: r.Markup})
 			continue
 		}
-		if r.IsFullLine {
-			delRows[r.StartRow] = true
-			continue
+		spans[r.StartRow] = append(spans[r.StartRow], span{start: r.StartCol, end: toLineEnd, markup: r.Markup})
+		for row := r.StartRow + 1; row < r.EndRow; row++ {
+			spans[row] = append(spans[row], span{start: 0, end: toLineEnd, markup: r.Markup})
 		}
-		inlineEdits = append(inlineEdits, inlineEdit{
-			row:      r.StartRow,
-			startCol: r.StartCol,
-			endCol:   r.EndCol,
-		})
+		last := span{start: 0, end: r.EndCol, markup: r.Markup}
+		if int(r.StartRow) < len(lines) {
+			line := lines[r.StartRow]
+			last.indent = line[:len(line)-len(bytes.TrimLeft(line, " \t"))]
+		}
+		spans[r.EndRow] = append(spans[r.EndRow], last)
 	}
 
 	out := make([][]byte, 0, len(lines))
+	b := blanks{opts: opts, leading: true}
+	cleared := 0
 	for i, line := range lines {
-		row := uint32(i)
-		if delRows[row] {
+		rowSpans, ok := spans[uint32(i)]
+		if !ok {
+			out = b.emit(out, line)
 			continue
 		}
-		trimmed := applyInlineEdits(line, inlineEdits, row)
-		if trimmed == nil {
+		spliced := splice(line, rowSpans, opts)
+		if spliced == nil && opts.keepsLines() && lineEnding(line) > 0 {
+			spliced = line[len(line)-lineEnding(line):]
+			cleared++
+		}
+		if spliced == nil {
+			b.touched = true
 			continue
 		}
-		out = append(out, trimmed)
+		out = b.emit(out, spliced)
 	}
-	return out
+	out = b.finish(out)
+	return out, Stats{BlankLines: b.dropped, ClearedLines: cleared}
+}
+
+type blanks struct {
+	opts    Options
+	pending [][]byte
+	leading bool
+	touched bool
+	dropped int
 }
 
-func applyInlineEdits(line []byte, edits []inlineEdit, row uint32) []byte {
-	type span struct {
-		start, end uint32
+func (b *blanks) emit(out [][]byte, line []byte) [][]byte {
+	if len(line) == 0 {
+		return out
 	}
-	var spans []span
-	for _, e := range edits {
-		if e.row == row {
-			spans = append(spans, span{e.startCol, e.endCol})
-		}
+	if isBlank(line) {
+		b.pending = append(b.pending, line)
+		return out
 	}
-	if len(spans) == 0 {
-		return line
+	keep := len(b.pending)
+	switch {
+	case b.opts.Blanks == BlanksTrim && b.leading:
+		keep = 0
+	case b.opts.Blanks == BlanksCollapse && b.touched && keep > b.opts.MaxBlank:
+		keep = b.opts.MaxBlank
 	}
+	out = b.flush(out, keep)
+	b.leading = false
+	b.touched = false
+	return append(out, line)
+}
 
-	hasNewline := len(line) > 0 && line[len(line)-1] == '\n'
-	content := line
-	if hasNewline {
-		content = line[:len(line)-1]
+func (b *blanks) finish(out [][]byte) [][]byte {
+	keep := len(b.pending)
+	switch {
+	case b.opts.Blanks == BlanksTrim:
+		keep = 0
+	case b.opts.Blanks == BlanksCollapse && b.touched && keep > b.opts.MaxBlank:
+		keep = b.opts.MaxBlank
 	}
+	return b.flush(out, keep)
+}
+
+func (b *blanks) flush(out [][]byte, keep int) [][]byte {
+	out = append(out, b.pending[:keep]...)
+	b.dropped += len(b.pending) - keep
+	b.pending = b.pending[:0]
+	return out
+}
+
+func isBlank(line []byte) bool {
+	for _, c := range line {
+		if c != ' ' && c != '\t' && c != '\r' && c != '\n' {
+			return false
+		}
+	}
+	return true
+}
+
+func splice(line []byte, spans []span, opts Options) []byte {
+	content, ending := line[:len(line)-lineEnding(line)], line[len(line)-lineEnding(line):]
+
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
 
 	result := make([]byte, 0, len(content))
-	for i := uint32(0); i < uint32(len(content)); i++ {
-		inSpan := false
-		for _, s := range spans {
-			if i >= s.start && i < s.end {
-				inSpan = true
-				break
+	pos := uint32(0)
+	for i, s := range spans {
+		start, end := clamp(s.start, content), clamp(s.end, content)
+		if start > pos {
+			result = append(result, content[pos:start]...)
+		}
+		if opts.PreserveColumns {
+			result = appendBlanks(result, content[max(start, pos):max(end, pos)])
+		}
+		if end > pos {
+			pos = end
+		}
+		if i+1 < len(spans) && clamp(spans[i+1].start, content) <= pos {
+			continue
+		}
+		if !opts.PreserveColumns && !s.markup && int(pos) < len(content) && content[pos] == '}' && !isBlank(result) {
+			result = trimRight(result)
+		}
+		if !opts.PreserveColumns && (isBlank(result) || isSpace(result[len(result)-1]) || !s.markup && start > 0 && content[start-1] == '{' && result[len(result)-1] == '{') {
+			for int(pos) < len(content) && isSpace(content[pos]) {
+				pos++
+			}
+			if s.indent != nil && isBlank(result) {
+				result = append(result[:0], s.indent...)
 			}
 		}
-		if !inSpan {
-			result = append(result, content[i])
+		if !s.markup && !opts.PreserveColumns && len(result) > 0 && int(pos) < len(content) && fuses(result[len(result)-1], content[pos]) {
+			result = append(result, ' ')
 		}
 	}
+	if int(pos) < len(content) {
+		result = append(result, content[pos:]...)
+	}
 
 	result = trimRight(result)
 	if len(result) == 0 {
 		return nil
 	}
-	if hasNewline {
-		result = append(result, '\n')
+	return append(result, ending...)
+}
+
+func lineEnding(line []byte) int {
+	switch {
+	case bytes.HasSuffix(line, []byte("\r\n")):
+		return 2
+	case bytes.HasSuffix(line, []byte("\n")), bytes.HasSuffix(line, []byte("\r")):
+		return 1
+	}
+	return 0
+}
+
+func appendBlanks(dst, removed []byte) []byte {
+	for _, c := range removed {
+		if c != '\t' {
+			c = ' '
+		}
+		dst = append(dst, c)
 	}
-	return result
+	return dst
+}
+
+func clamp(col uint32, content []byte) uint32 {
+	if int(col) > len(content) {
+		return uint32(len(content))
+	}
+	return col
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t'
 }
 
 func trimRight(b []byte) []byte {
@@ -137,3 +343,33 @@ func trimRight(b []byte) []byte {
 	}
 	return b[:end]
 }
+
+func fuses(before, after byte) bool {
+	switch {
+	case isWord(before) && isWord(after):
+		return true
+	case isOperator(before) && isOperator(after):
+		return true
+	case isQuote(before) && isQuote(after):
+		return true
+	case isDigit(before) && after == '.', before == '.' && isDigit(after):
+		return true
+	}
+	return false
+}
+
+func isWord(b byte) bool {
+	return b == '_' || b == '$' || b >= 0x80 || isDigit(b) || (b|0x20 >= 'a' && b|0x20 <= 'z')
+}
+
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+func isOperator(b byte) bool {
+	return strings.IndexByte("!#%&*+-/:<=>?@\\^|~", b) >= 0
+}
+
+func isQuote(b byte) bool {
+	return b == '"' || b == '\'' || b == '`'
+}
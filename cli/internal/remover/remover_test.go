@@ -1,9 +1,13 @@
 package remover
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 
+	"github.com/KashifKhn/remove-comments/cli/internal/languages"
 	"github.com/KashifKhn/remove-comments/cli/internal/parser"
+	"github.com/KashifKhn/remove-comments/cli/internal/verify"
 )
 
 func TestRemove_NoComments(t *testing.T) {
@@ -130,3 +134,423 @@ func TestTrimRight(t *testing.T) {
 		}
 	}
 }
+
+func TestRemove_MultiLineBlock_KeepsCodeOnBothEnds(t *testing.T) {
+	src := []byte("int x = 1; /* starts here\nstill comment\nends here */ int y = 2;\nint z = 3;\n")
+	ranges := []parser.CommentRange{
+		{StartRow: 0, StartCol: 11, EndRow: 2, EndCol: 12, IsMultiLine: true},
+	}
+	want := "int x = 1;\nint y = 2;\nint z = 3;\n"
+	got := Remove(src, ranges)
+	if string(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestRemove_MultiLineBlock_CodeAfterOnly(t *testing.T) {
+	src := []byte("a {\n  /* one\n     two */ color: red;\n}\n")
+	ranges := []parser.CommentRange{
+		{StartRow: 1, StartCol: 2, EndRow: 2, EndCol: 11, IsMultiLine: true},
+	}
+	want := "a {\n  color: red;\n}\n"
+	got := Remove(src, ranges)
+	if string(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestRemove_LeadingBlockKeepsIndentation(t *testing.T) {
+	tests := []struct {
+		name   string
+		src    string
+		ranges []parser.CommentRange
+		want   string
+	}{
+		{"c statement after a leading comment", "int f() {\n  /* a */ int y = 2;\n}\n",
+			[]parser.CommentRange{{StartRow: 1, StartCol: 2, EndRow: 1, EndCol: 9}},
+			"int f() {\n  int y = 2;\n}\n"},
+		{"comment at column zero", "/* a */  x;\n",
+			[]parser.CommentRange{{StartRow: 0, StartCol: 0, EndRow: 0, EndCol: 7}},
+			"x;\n"},
+		{"css declaration before a trailing comment", ".b { x; /* c */ }\n",
+			[]parser.CommentRange{{StartRow: 0, StartCol: 8, EndRow: 0, EndCol: 15}},
+			".b { x; }\n"},
+		{"comment between tokens", "f(a, /* b */ c);\n",
+			[]parser.CommentRange{{StartRow: 0, StartCol: 5, EndRow: 0, EndCol: 12}},
+			"f(a, c);\n"},
+		{"code after a comment that started after code", "{\n  x; /* a\n  b */ y;\n}\n",
+			[]parser.CommentRange{{StartRow: 1, StartCol: 5, EndRow: 2, EndCol: 6, IsMultiLine: true}},
+			"{\n  x;\n  y;\n}\n"},
+		{"tabs", "\t/* a */\tx;\n",
+			[]parser.CommentRange{{StartRow: 0, StartCol: 1, EndRow: 0, EndCol: 8}},
+			"\tx;\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Remove([]byte(tt.src), tt.ranges); string(got) != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemove_MultiLineBlock_IndentedAlone_Dropped(t *testing.T) {
+	src := []byte("f() {\n\t/*\n\t * note\n\t */\n\treturn\n}\n")
+	ranges := []parser.CommentRange{
+		{StartRow: 1, StartCol: 1, EndRow: 3, EndCol: 4, IsMultiLine: true},
+	}
+	want := "f() {\n\treturn\n}\n"
+	got := Remove(src, ranges)
+	if string(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestRemove_TwoCommentsOnOneLine(t *testing.T) {
+	src := []byte("a /* x */ b /* y */\n")
+	ranges := []parser.CommentRange{
+		{StartRow: 0, StartCol: 12, EndRow: 0, EndCol: 19},
+		{StartRow: 0, StartCol: 2, EndRow: 0, EndCol: 9},
+	}
+	want := "a b\n"
+	got := Remove(src, ranges)
+	if string(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestRemove_BlockEndsWhereAnotherStarts(t *testing.T) {
+	src := []byte("x; /* a\nb */ /* c\nd */ y;\n")
+	ranges := []parser.CommentRange{
+		{StartRow: 0, StartCol: 3, EndRow: 1, EndCol: 4, IsMultiLine: true},
+		{StartRow: 1, StartCol: 5, EndRow: 2, EndCol: 4, IsMultiLine: true},
+	}
+	want := "x;\ny;\n"
+	got := Remove(src, ranges)
+	if string(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestRemove_InlineBlockKeepsTokensApart(t *testing.T) {
+	tests := []struct {
+		ext  string
+		src  string
+		want string
+	}{
+		{".js", "let a = b/**/c;\n", "let a = b c;\n"},
+		{".js", "function f() { return/*x*/value; }\n", "function f() { return value; }\n"},
+		{".js", "x = a+/**/+b;\n", "x = a+ +b;\n"},
+		{".js", "x = a/**/.b;\n", "x = a.b;\n"},
+		{".ts", "let n: number = 1/**/.5;\n", "let n: number = 1 .5;\n"},
+		{".tsx", "const x = <div>{a/**/b}</div>;\n", "const x = <div>{a b}</div>;\n"},
+		{".java", "class A { int/*x*/y; }\n", "class A { int y; }\n"},
+		{".c", "int/**/x = a-/**/-b;\n", "int x = a- -b;\n"},
+		{".cpp", "auto/**/x = a/**//**/b;\n", "auto x = a b;\n"},
+		{".go", "package p\n\nvar x = a/**/b\n", "package p\n\nvar x = a b\n"},
+		{".go", "package p\n\nvar x = f(/**/a)\n", "package p\n\nvar x = f(a)\n"},
+		{".css", "a { margin:0/**/auto; }\n", "a { margin:0 auto; }\n"},
+		{".css", "a/**/.b { color: red; }\n", "a.b { color: red; }\n"},
+		{".lua", "local x = a--[[c]]..b\n", "local x = a..b\n"},
+		{".dart", "var x = a/**/b;\n", "var x = a b;\n"},
+		{".html", "<p>foo<!-- x -->bar</p>\n", "<p>foobar</p>\n"},
+		{".php", "<p>foo<!-- x -->bar<?= $a/**/ ?></p>\n", "<p>foobar<?= $a ?></p>\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ext+" "+tt.want, func(t *testing.T) {
+			lang, ok := languages.Get(tt.ext)
+			if !ok {
+				t.Fatalf("no language for %s", tt.ext)
+			}
+			ranges, err := parser.Parse([]byte(tt.src), lang)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := Remove([]byte(tt.src), ranges)
+			if string(got) != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			if err := verify.Check([]byte(tt.src), got, lang); err != nil {
+				t.Errorf("verify rejected the removal: %v", err)
+			}
+		})
+	}
+}
+
+func TestRemove_MarkupJoinsText(t *testing.T) {
+	src := []byte("foo/* x */bar\n")
+	ranges := []parser.CommentRange{{StartRow: 0, StartCol: 3, EndRow: 0, EndCol: 10}}
+	if got := string(Remove(src, ranges)); got != "foo bar\n" {
+		t.Errorf("code: got %q", got)
+	}
+	ranges[0].Markup = true
+	if got := string(Remove(src, ranges)); got != "foobar\n" {
+		t.Errorf("markup: got %q", got)
+	}
+}
+
+func TestFuses(t *testing.T) {
+	tests := []struct {
+		before, after byte
+		want          bool
+	}{
+		{'a', 'b', true},
+		{'0', 'x', true},
+		{'_', '$', true},
+		{'+', '+', true},
+		{'/', '/', true},
+		{'/', '*', true},
+		{'\'', '\'', true},
+		{'1', '.', true},
+		{'.', '5', true},
+		{'a', '.', false},
+		{'(', 'a', false},
+		{'a', ')', false},
+		{'a', '+', false},
+		{',', ',', false},
+	}
+	for _, tt := range tests {
+		if got := fuses(tt.before, tt.after); got != tt.want {
+			t.Errorf("fuses(%q, %q) = %v, want %v", tt.before, tt.after, got, tt.want)
+		}
+	}
+}
+
+func TestRemoveWith_BlankPolicy(t *testing.T) {
+	src := "// header\n\npackage p\n\nfunc a() {}\n\n// between\n\n\nfunc b() {}\n\n\n\nvar untouched = 1\n// tail\n\n"
+	ranges := []parser.CommentRange{
+		{StartRow: 0, EndRow: 0, EndCol: 9},
+		{StartRow: 6, EndRow: 6, EndCol: 10},
+		{StartRow: 14, EndRow: 14, EndCol: 7},
+	}
+	tests := []struct {
+		name   string
+		opts   Options
+		want   string
+		blanks int
+	}{
+		{"preserve", Options{}, "\npackage p\n\nfunc a() {}\n\n\n\nfunc b() {}\n\n\n\nvar untouched = 1\n\n", 0},
+		{"collapse to one", Options{Blanks: BlanksCollapse, MaxBlank: 1}, "\npackage p\n\nfunc a() {}\n\nfunc b() {}\n\n\n\nvar untouched = 1\n\n", 2},
+		{"collapse to zero", Options{Blanks: BlanksCollapse}, "package p\n\nfunc a() {}\nfunc b() {}\n\n\n\nvar untouched = 1\n", 5},
+		{"trim", Options{Blanks: BlanksTrim}, "package p\n\nfunc a() {}\n\n\n\nfunc b() {}\n\n\n\nvar untouched = 1\n", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, stats := RemoveWith([]byte(src), ranges, tt.opts)
+			if string(got) != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			if stats.BlankLines != tt.blanks {
+				t.Errorf("dropped %d blank lines, want %d", stats.BlankLines, tt.blanks)
+			}
+		})
+	}
+}
+
+func TestRemoveWith_TrimAllBlank(t *testing.T) {
+	src := []byte("// only\n\n")
+	ranges := []parser.CommentRange{{StartRow: 0, EndRow: 0, EndCol: 7}}
+	got, stats := RemoveWith(src, ranges, Options{Blanks: BlanksTrim})
+	if len(got) != 0 || stats.BlankLines != 1 {
+		t.Errorf("got %q with %d blank lines dropped", got, stats.BlankLines)
+	}
+}
+
+func TestParseBlankPolicy(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    BlankPolicy
+		wantErr bool
+	}{
+		{"", BlanksPreserve, false},
+		{"preserve", BlanksPreserve, false},
+		{"collapse", BlanksCollapse, false},
+		{"trim", BlanksTrim, false},
+		{"squash", BlanksPreserve, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseBlankPolicy(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseBlankPolicy(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("ParseBlankPolicy(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRemoveWith_PreserveLines(t *testing.T) {
+	src := "// header\nx := 1 // trailing\n/* a\n   b */ y := 2\n/* c\n */\nz := 3\n"
+	ranges := []parser.CommentRange{
+		{StartRow: 0, EndRow: 0, EndCol: 9},
+		{StartRow: 1, StartCol: 7, EndRow: 1, EndCol: 18},
+		{StartRow: 2, EndRow: 3, EndCol: 7, IsMultiLine: true},
+		{StartRow: 4, EndRow: 5, EndCol: 3, IsMultiLine: true},
+	}
+	tests := []struct {
+		name    string
+		opts    Options
+		want    string
+		cleared int
+	}{
+		{"lines", Options{PreserveLines: true}, "\nx := 1\n\ny := 2\n\n\nz := 3\n", 4},
+		{"columns", Options{PreserveColumns: true}, "\nx := 1\n\n        y := 2\n\n\nz := 3\n", 4},
+		{"lines ignore blank policy", Options{PreserveLines: true, Blanks: BlanksTrim}, "\nx := 1\n\ny := 2\n\n\nz := 3\n", 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, stats := RemoveWith([]byte(src), ranges, tt.opts)
+			if string(got) != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			if stats.ClearedLines != tt.cleared {
+				t.Errorf("cleared %d lines, want %d", stats.ClearedLines, tt.cleared)
+			}
+			if strings.Count(string(got), "\n") != strings.Count(src, "\n") {
+				t.Errorf("line count changed: %q", got)
+			}
+		})
+	}
+}
+
+func TestRemoveWith_PreserveColumns_InlineBlock(t *testing.T) {
+	src := []byte("a/**/b\tc /*\tx */ d\n")
+	ranges := []parser.CommentRange{
+		{StartRow: 0, StartCol: 1, EndRow: 0, EndCol: 5},
+		{StartRow: 0, StartCol: 9, EndRow: 0, EndCol: 16},
+	}
+	got, _ := RemoveWith(src, ranges, Options{PreserveColumns: true})
+	want := "a    b\tc   \t     d\n"
+	if string(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if len(got) != len(src) {
+		t.Errorf("length changed from %d to %d", len(src), len(got))
+	}
+}
+
+func TestRemove_LineEndingsAndBOM(t *testing.T) {
+	samples := []struct {
+		ext   string
+		lines []string
+		want  []string
+	}{
+		{".py", []string{"# header", "x = 1  # trailing", "y = 2"}, []string{"", "x = 1", "y = 2"}},
+		{".js", []string{"a(); /* one", "two */ b();", "// c", "d();"}, []string{"a();", "b();", "", "d();"}},
+		{".go", []string{"package p", "", "var x = 1 // one", "// two"}, []string{"package p", "", "var x = 1", ""}},
+	}
+	endings := map[string][]string{
+		"lf":    {"\n"},
+		"crlf":  {"\r\n"},
+		"cr":    {"\r"},
+		"mixed": {"\r\n", "\n", "\r"},
+	}
+	for _, sample := range samples {
+		for name, eol := range endings {
+			for _, withBOM := range []bool{false, true} {
+				var src, want strings.Builder
+				if withBOM {
+					src.WriteString("\xef\xbb\xbf")
+					want.WriteString("\xef\xbb\xbf")
+				}
+				for i, line := range sample.lines {
+					ending := eol[i%len(eol)]
+					src.WriteString(line + ending)
+					if sample.want[i] != "" || line == "" {
+						want.WriteString(sample.want[i] + ending)
+					}
+				}
+				t.Run(fmt.Sprintf("%s %s bom=%v", sample.ext, name, withBOM), func(t *testing.T) {
+					lang, _ := languages.Get(sample.ext)
+					ranges, err := parser.Parse([]byte(src.String()), lang)
+					if err != nil {
+						t.Fatal(err)
+					}
+					got := Remove([]byte(src.String()), ranges)
+					if string(got) != want.String() {
+						t.Errorf("got %q, want %q", got, want.String())
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestRemoveWith_PreserveLines_KeepsEnding(t *testing.T) {
+	src := []byte("// a\r\nx\r// b\r\n")
+	ranges := []parser.CommentRange{
+		{StartRow: 0, EndRow: 0, EndCol: 4},
+		{StartRow: 2, EndRow: 2, EndCol: 4},
+	}
+	got, _ := RemoveWith(src, ranges, Options{PreserveLines: true})
+	if string(got) != "\r\nx\r\r\n" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestRemove_Rust_KeepsDocTests(t *testing.T) {
+	src := []byte("/// ```\n/// assert!(f());\n/// ```\n/* helper /* nested */ */\nfn f() -> bool { true /* always */ }\n")
+	lang, _ := languages.Get(".rs")
+	ranges, err := parser.Parse(src, lang)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var remove []parser.CommentRange
+	for _, r := range ranges {
+		if r.Kind != parser.KindDoc {
+			remove = append(remove, r)
+		}
+	}
+	want := "/// ```\n/// assert!(f());\n/// ```\nfn f() -> bool { true }\n"
+	if got := string(Remove(src, remove)); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestRemove_JSXContainerKeptComment(t *testing.T) {
+	src := []byte("const a = <p>x{/* a */ /* keep */}z</p>;\n")
+	lang, _ := languages.Get(".jsx")
+	ranges, err := parser.Parse(src, lang)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var remove []parser.CommentRange
+	for _, r := range ranges {
+		if !strings.Contains(string(r.Text(src)), "keep") {
+			remove = append(remove, r)
+		}
+	}
+	want := "const a = <p>x{/* keep */}z</p>;\n"
+	if got := string(Remove(src, remove)); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestRemove_JSXCommentContainers(t *testing.T) {
+	tests := []struct {
+		ext  string
+		src  string
+		want string
+	}{
+		{".jsx", "const a = (\n  <div>\n    {/* note */}\n    <p>hi</p>\n  </div>\n);\n", "const a = (\n  <div>\n    <p>hi</p>\n  </div>\n);\n"},
+		{".jsx", "const a = <p>x{/* y */}z</p>;\n", "const a = <p>xz</p>;\n"},
+		{".js", "const a = <p>{\n  // why\n}</p>;\n", "const a = <p>\n</p>;\n"},
+		{".tsx", "const a = <b>one {/* two */} three</b>;\n", "const a = <b>one three</b>;\n"},
+		{".tsx", "const a = <b>{x /* keep x */}</b>;\n", "const a = <b>{x}</b>;\n"},
+		{".jsx", "const a = <p>{/* c */ x}</p>;\n", "const a = <p>{x}</p>;\n"},
+		{".jsx", "const a = <p>x{/* a */ /* b */}z</p>;\n", "const a = <p>xz</p>;\n"},
+		{".jsx", "const a = (\n  <div>\n    {/* a */\n     // b\n    }\n  </div>\n);\n", "const a = (\n  <div>\n  </div>\n);\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ext+" "+tt.want, func(t *testing.T) {
+			lang, _ := languages.Get(tt.ext)
+			ranges, err := parser.Parse([]byte(tt.src), lang)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(Remove([]byte(tt.src), ranges)); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,299 @@
+package verify
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	sitter "github.com/smacker/go-tree-sitter"
+
+	"github.com/KashifKhn/remove-comments/cli/internal/languages"
+	"github.com/KashifKhn/remove-comments/cli/internal/parser"
+)
+
+var continuation = regexp.MustCompile(`\\\r?\n`)
+
+type nodeKey struct {
+	start, end uint32
+	typ        string
+}
+
+type token struct {
+	typ   string
+	depth int
+	leaf  bool
+	text  string
+	raw   string
+	row   uint32
+}
+
+type shape struct {
+	tokens    []token
+	errors    int
+	errorRows []uint32
+	join      bool
+	glued     bool
+	words     []string
+}
+
+func Check(before, after []byte, cfg languages.LangConfig) error {
+	want, err := flatten(before, cfg)
+	if err != nil {
+		return err
+	}
+	got, err := flatten(after, cfg)
+	if err != nil {
+		return err
+	}
+
+	if got.errors > want.errors {
+		return fmt.Errorf("verify: removal introduces a syntax error at line %d", got.errorRows[len(want.errorRows)]+1)
+	}
+	msg := compare(want.tokens, got.tokens)
+	if msg == "" || want.glued && slices.Equal(want.words, got.words) {
+		return nil
+	}
+	if want.errors > 0 {
+		return fmt.Errorf("verify: syntax tree changed at %s (the original already has %d parse error(s))", msg, want.errors)
+	}
+	return fmt.Errorf("verify: syntax tree changed at %s", msg)
+}
+
+func compare(want, got []token) string {
+	for i := 0; i < len(want) || i < len(got); i++ {
+		switch {
+		case i >= len(got):
+			return fmt.Sprintf("line %d: %s was dropped", want[i].row+1, want[i].typ)
+		case i >= len(want):
+			return fmt.Sprintf("line %d: unexpected %s", got[i].row+1, got[i].typ)
+		}
+		w, g := want[i], got[i]
+		switch {
+		case w.typ != g.typ:
+			return fmt.Sprintf("line %d: %s became %s", w.row+1, w.typ, g.typ)
+		case w.depth != g.depth || w.leaf != g.leaf:
+			return fmt.Sprintf("line %d: %s moved or changed shape", w.row+1, w.typ)
+		case w.text != g.text && w.leaf:
+			return fmt.Sprintf("line %d: %q became %q", w.row+1, w.text, g.text)
+		case w.text != g.text:
+			return fmt.Sprintf("line %d: text of %s changed", w.row+1, w.typ)
+		}
+	}
+	return ""
+}
+
+func flatten(src []byte, cfg languages.LangConfig) (shape, error) {
+	tree, err := parser.Tree(src, cfg)
+	if err != nil {
+		return shape{}, fmt.Errorf("verify: %w", err)
+	}
+	defer tree.Close()
+
+	comments, err := commentNodes(tree.RootNode(), cfg)
+	if err != nil {
+		return shape{}, err
+	}
+
+	injections := parser.Injections(src, cfg.Name, tree.RootNode())
+	injected := map[nodeKey]bool{}
+	for _, inj := range injections {
+		injected[nodeKey{inj.StartByte, inj.EndByte, ""}] = true
+	}
+
+	var s shape
+	walk(tree.RootNode(), 0, src, cfg.Markup, comments, injected, &s)
+	lexemes(tree.RootNode(), src, cfg.Markup, comments, injected, &s.words)
+	for _, inj := range injections {
+		sub, err := flatten(src[inj.StartByte:inj.EndByte], inj.Lang)
+		if err != nil {
+			return shape{}, err
+		}
+		for _, t := range sub.tokens {
+			t.row += inj.Start.Row
+			s.tokens = append(s.tokens, t)
+		}
+		for _, row := range sub.errorRows {
+			s.errorRows = append(s.errorRows, row+inj.Start.Row)
+		}
+		s.errors += sub.errors
+		s.glued = s.glued || sub.glued
+		s.words = append(s.words, sub.words...)
+	}
+	return s, nil
+}
+
+func commentNodes(root *sitter.Node, cfg languages.LangConfig) (map[nodeKey]string, error) {
+	q, err := cfg.CompileQuery()
+	if err != nil {
+		return nil, fmt.Errorf("verify: query compile: %w", err)
+	}
+
+	qc := sitter.NewQueryCursor()
+	defer qc.Close()
+	qc.Exec(q, root)
+
+	comments := map[nodeKey]string{}
+	var containers []*sitter.Node
+	for {
+		m, ok := qc.NextMatch()
+		if !ok {
+			break
+		}
+		for _, c := range m.Captures {
+			if name := q.CaptureNameForId(c.Index); name == "container" {
+				containers = append(containers, c.Node)
+			} else {
+				comments[keyOf(c.Node)] = name
+			}
+		}
+	}
+	for _, c := range containers {
+		if onlyComments(c, comments) {
+			comments[keyOf(c)] = "container"
+		}
+	}
+	return comments, nil
+}
+
+func onlyComments(n *sitter.Node, comments map[nodeKey]string) bool {
+	for i := 0; i < int(n.NamedChildCount()); i++ {
+		if comments[keyOf(n.NamedChild(i))] == "" {
+			return false
+		}
+	}
+	return true
+}
+
+func keyOf(n *sitter.Node) nodeKey {
+	return nodeKey{n.StartByte(), n.EndByte(), n.Type()}
+}
+
+func walk(n *sitter.Node, depth int, src []byte, markup bool, comments map[nodeKey]string, injected map[nodeKey]bool, s *shape) {
+	if name := comments[keyOf(n)]; name != "" {
+		s.join = s.join || name == "container"
+		s.glued = s.glued || glued(n, src)
+		return
+	}
+	if injected[nodeKey{n.StartByte(), n.EndByte(), ""}] {
+		s.tokens = append(s.tokens, token{typ: n.Type(), depth: depth, leaf: true, row: n.StartPoint().Row})
+		return
+	}
+	if n.IsError() || n.IsMissing() {
+		s.errors++
+		s.errorRows = append(s.errorRows, n.StartPoint().Row)
+	}
+
+	count := int(n.ChildCount())
+	t := token{typ: n.Type(), depth: depth, leaf: true, row: n.StartPoint().Row}
+	for i := 0; i < count && t.leaf; i++ {
+		t.leaf = comments[keyOf(n.Child(i))] != ""
+	}
+	t.text = ownText(n, count, src, comments)
+	if t.leaf {
+		t.raw = n.Content(src)
+	}
+	join := s.join
+	s.join = false
+	if last := len(s.tokens) - 1; join && t.leaf && last >= 0 && mergesWith(s.tokens[last], t) {
+		s.tokens[last].raw += t.raw
+		s.tokens[last].text = normalize([]byte(s.tokens[last].raw))
+		return
+	}
+	if markup {
+		t.text = strings.Join(strings.FieldsFunc(t.text, isSeparator), "")
+		if last := len(s.tokens) - 1; t.leaf && last >= 0 && mergesWith(s.tokens[last], t) {
+			s.tokens[last].text += t.text
+			return
+		}
+	}
+	s.tokens = append(s.tokens, t)
+
+	for i := 0; i < count; i++ {
+		walk(n.Child(i), depth+1, src, markup, comments, injected, s)
+	}
+}
+
+func glued(n *sitter.Node, src []byte) bool {
+	start, end := int(n.StartByte()), int(n.EndByte())
+	return start > 0 && end < len(src) && !unicode.IsSpace(rune(src[start-1])) && !unicode.IsSpace(rune(src[end]))
+}
+
+func lexemes(n *sitter.Node, src []byte, markup bool, comments map[nodeKey]string, injected map[nodeKey]bool, words *[]string) {
+	if comments[keyOf(n)] != "" {
+		return
+	}
+	count := int(n.ChildCount())
+	if count == 0 || injected[nodeKey{n.StartByte(), n.EndByte(), ""}] {
+		if text := n.Content(src); markup {
+			*words = append(*words, strings.Fields(text)...)
+		} else if text != "" {
+			*words = append(*words, text)
+		}
+		return
+	}
+	pos := n.StartByte()
+	for i := 0; i < count; i++ {
+		child := n.Child(i)
+		if child.StartByte() > pos {
+			*words = append(*words, strings.Fields(string(src[pos:child.StartByte()]))...)
+		}
+		lexemes(child, src, markup, comments, injected, words)
+		if child.EndByte() > pos {
+			pos = child.EndByte()
+		}
+	}
+	if n.EndByte() > pos {
+		*words = append(*words, strings.Fields(string(src[pos:n.EndByte()]))...)
+	}
+}
+
+func ownText(n *sitter.Node, count int, src []byte, comments map[nodeKey]string) string {
+	var parts []string
+	var gap []byte
+	var last string
+	join := false
+	pos := n.StartByte()
+	for i := 0; i < count; i++ {
+		child := n.Child(i)
+		if child.StartByte() > pos {
+			gap = append(gap, src[pos:child.StartByte()]...)
+		}
+		switch name := comments[keyOf(child)]; {
+		case name != "":
+			gap = append(gap, ' ')
+			join = join || name == "container"
+		case join && child.ChildCount() == 0 && child.Type() == last:
+			join = false
+		default:
+			parts = append(parts, normalize(gap))
+			gap = gap[:0]
+			last, join = child.Type(), false
+			if child.ChildCount() > 0 {
+				last = ""
+			}
+		}
+		if child.EndByte() > pos {
+			pos = child.EndByte()
+		}
+	}
+	if n.EndByte() > pos {
+		gap = append(gap, src[pos:n.EndByte()]...)
+	}
+	parts = append(parts, normalize(gap))
+	return strings.Join(parts, "\x00")
+}
+
+func normalize(b []byte) string {
+	text := continuation.ReplaceAllString(string(b), " ")
+	return strings.Join(strings.Fields(text), " ")
+}
+
+func isSeparator(r rune) bool {
+	return r == 0 || unicode.IsSpace(r)
+}
+
+func mergesWith(prev, t token) bool {
+	return prev.leaf && prev.typ == t.typ && prev.depth == t.depth
+}
@@ -0,0 +1,167 @@
+package verify
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/KashifKhn/remove-comments/cli/internal/languages"
+	"github.com/KashifKhn/remove-comments/cli/internal/parser"
+	"github.com/KashifKhn/remove-comments/cli/internal/remover"
+)
+
+func lang(t *testing.T, ext string) languages.LangConfig {
+	t.Helper()
+	cfg, ok := languages.Get(ext)
+	if !ok {
+		t.Fatalf("no language for %s", ext)
+	}
+	return cfg
+}
+
+func TestCheck_RemovalPreservesTree(t *testing.T) {
+	tests := []struct {
+		ext string
+		src string
+	}{
+		{".go", "package main\n\n// Add adds.\nfunc Add(a, b int) int {\n\treturn a + b // sum\n}\n"},
+		{".js", "/** doc */\nfunction f(a, /* b */ c) {\n  return a/**/+c; // done\n}\n"},
+		{".py", "# header\ndef f():\n    x = 1  # inline\n    return x\n"},
+		{".c", "int main(void) {\n\t/* block\n\t   more */ return 0;\n}\n"},
+		{".css", "a { color: red; /* why */ } /* end */\n"},
+		{".css", "a { margin:0/**/auto; }\n"},
+		{".lua", "-- top\nlocal x = 1 --[[ inline ]] + 2\n"},
+		{".html", "<p>foo<!-- x -->bar</p>\n<!-- alone -->\n<div>\n  text\n  <!-- inside -->\n  more\n</div>\n"},
+		{".sh", "#!/bin/sh\n# note\necho hi # trailing\n"},
+		{".vue", "<template>\n  <!-- t -->\n  <p>{{ x }}</p>\n</template>\n<script setup lang=\"ts\">\nconst x = 1 // c\n</script>\n<style lang=\"scss\">\n// s\n.a { .b { color: red; } }\n</style>\n"},
+		{".svelte", "<script>\n  let x = 1; // c\n</script>\n<!-- t -->\n<p>{x}</p>\n<style>\n  /* s */ p { color: red; }\n</style>\n"},
+		{".scss", "// top\n$a: 1px; // trailing\n.a { margin: $a; }\n"},
+		{".jsx", "const a = (\n  <div>\n    {/* note */}\n    <p>x{/* y */}z</p>\n  </div>\n);\n"},
+		{".tsx", "const a = <b>one {/* two */} three {x /* x */}</b>;\n"},
+		{".jsx", "const a = <p>x{/* a */ /* b */}z{/* c */ y /* d */}</p>;\n"},
+		{".rb", "# frozen_string_literal: true\n=begin\nnotes\n=end\ndef f(x) = x # one\n"},
+		{".php", "<p>hi</p>\n<?php\n/** d */\nfunction f($a) { return $a; /* r */ } // e ?>\n<p>bye<!-- x --></p>\n<!-- y -->\n"},
+		{".ex", "defmodule A do\n  @moduledoc \"M\"\n  # c\n  @doc \"f\"\n  def f, do: 1 # t\nend\n"},
+		{".ml", "(** doc *)\nlet x = 1 (* a (* n *) b *) + 2\n"},
+		{".elm", "module A exposing (x)\n\n{-| Doc -}\nx = 1 {- a -} + 2 -- t\n"},
+		{".hs", "{-# LANGUAGE GADTs #-}\n-- | Doc\nmodule A where\n\nf :: Int -> Int -- ^ arg\nf x = x {- a -} + 2 -- t\n  where\n    -- inner\n    y = 1\n"},
+		{".tf", "# a\nresource \"x\" \"y\" {\n  n = 1 // b\n  /* c */\n}\n"},
+		{".dockerfile", "# syntax=docker/dockerfile:1\n# stage\nFROM alpine\nRUN echo a \\\n  # gone\n  && echo b\n"},
+		{".proto", "syntax = \"proto3\";\n// a\nmessage A { int32 x = 1; /* b */ }\n"},
+		{".sql", "-- a\nSELECT 1 /* b */ + 2; -- c\n"},
+		{".cue", "// a\npackage a\nx: 1 // b\n"},
+		{".kt", "/** KDoc */\nfun f(a: Int) = a /* x */ + 1 // y\n"},
+		{".scala", "/* a /* n */ b */\nobject A { val x = 1 } // c\n"},
+		{".gradle", "plugins {\n  id \"java\" // c\n}\n/* x */\n"},
+		{".swift", "/// Doc\nlet x = 1 /* y */ + 2\n"},
+		{".cs", "/// <summary>X</summary>\nclass A { int x = 1; /* y */ }\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ext, func(t *testing.T) {
+			cfg := lang(t, tt.ext)
+			src := []byte(tt.src)
+			ranges, err := parser.Parse(src, cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			after := remover.Remove(src, ranges)
+			if string(after) == tt.src {
+				t.Fatal("expected removal to change the source")
+			}
+			if err := Check(src, after, cfg); err != nil {
+				t.Errorf("unexpected verify error: %v\noutput:\n%s", err, after)
+			}
+		})
+	}
+}
+
+func TestCheck_GluedTokens(t *testing.T) {
+	cfg := lang(t, ".js")
+	err := Check([]byte("let a = b/**/c;\n"), []byte("let a = bc;\n"), cfg)
+	if err == nil {
+		t.Fatal("expected error for glued tokens")
+	}
+}
+
+func TestCheck_JSXContainerWithCode(t *testing.T) {
+	cfg := lang(t, ".jsx")
+	err := Check([]byte("const a = <p>{/* a */ x}</p>;\n"), []byte("const a = <p></p>;\n"), cfg)
+	if err == nil {
+		t.Fatal("expected error for a dropped expression")
+	}
+}
+
+func TestCheck_ChangedString(t *testing.T) {
+	cfg := lang(t, ".go")
+	before := []byte("package p\n\nvar s = \"a // b\"\n")
+	after := []byte("package p\n\nvar s = \"a\"\n")
+	err := Check(before, after, cfg)
+	if err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Fatalf("expected error at line 3, got %v", err)
+	}
+}
+
+func TestCheck_NewSyntaxError(t *testing.T) {
+	cfg := lang(t, ".c")
+	before := []byte("int x = 1; /* c */\n")
+	after := []byte("int x = 1; /* c\n")
+	err := Check(before, after, cfg)
+	if err == nil || !strings.Contains(err.Error(), "syntax error") {
+		t.Fatalf("expected syntax error, got %v", err)
+	}
+}
+
+func TestCheck_ExistingErrorsTolerated(t *testing.T) {
+	cfg := lang(t, ".js")
+	before := []byte("function (( {\n// note\nlet x = 1;\n")
+	after := []byte("function (( {\nlet x = 1;\n")
+	if err := Check(before, after, cfg); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestCheck_MarkupMergesText(t *testing.T) {
+	cfg := lang(t, ".html")
+	before := []byte("<p>foo<!-- x -->bar</p>\n")
+	if err := Check(before, []byte("<p>foobar</p>\n"), cfg); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := Check(before, []byte("<p>foobaz</p>\n"), cfg); err == nil {
+		t.Error("expected error for changed text")
+	}
+}
+
+func TestCheck_HTMLInjection(t *testing.T) {
+	cfg := lang(t, ".html")
+	src := []byte("<script>\n  // setup\n  let a = b/**/c;\n</script>\n<style>\n  a { color: red; } /* x */\n</style>\n")
+	ranges, err := parser.Parse(src, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	after := remover.Remove(src, ranges)
+	want := "<script>\n  let a = b c;\n</script>\n<style>\n  a { color: red; }\n</style>\n"
+	if string(after) != want {
+		t.Fatalf("got %q, want %q", after, want)
+	}
+	if err := Check(src, after, cfg); err != nil {
+		t.Errorf("unexpected verify error: %v", err)
+	}
+	if err := Check(src, []byte("<script>\n  let a = bc;\n</script>\n<style>\n  a { color: red; }\n</style>\n"), cfg); err == nil {
+		t.Error("expected error for glued tokens inside <script>")
+	}
+}
+
+func TestCheck_GroovyOneLineDoc(t *testing.T) {
+	cfg := lang(t, ".groovy")
+	src := []byte("/** a */\ndef f() { 1 }\n/* b */\ndef g() { 2 }\n")
+	ranges, err := parser.Parse(src, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	after := remover.Remove(src, ranges)
+	if want := "def f() { 1 }\ndef g() { 2 }\n"; string(after) != want {
+		t.Errorf("got %q, want %q", after, want)
+	}
+	if err := Check(src, after, cfg); err != nil {
+		t.Errorf("unexpected verify error: %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package walker
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/KashifKhn/remove-comments/cli/internal/languages"
+)
+
+var cppMarkers = []struct {
+	re   *regexp.Regexp
+	name string
+}{
+	{regexp.MustCompile(`(?m)^\s*class\s+\w+\s*(final\s*)?[:{;]`), "class"},
+	{regexp.MustCompile(`(?m)^\s*(inline\s+)?namespace\b`), "namespace"},
+	{regexp.MustCompile(`\btemplate\s*<`), "template<"},
+	{regexp.MustCompile(`\bextern\s+"C\+\+"`), `extern "C++"`},
+	{regexp.MustCompile(`(?m)^\s*(public|private|protected)\s*:`), "access specifier"},
+	{regexp.MustCompile(`(?m)^\s*using\s+namespace\b`), "using namespace"},
+	{regexp.MustCompile(`\bstd::`), "std::"},
+	{regexp.MustCompile(`(?m)^\s*#\s*include\s*<[a-z_]+>`), "C++ standard header"},
+}
+
+const headerSize = 64 * 1024
+
+var (
+	cppSourceExts = []string{".cpp", ".cc", ".cxx", ".hpp", ".hh", ".hxx"}
+	cSourceExts   = []string{".c"}
+)
+
+type dirCache map[string]map[string]bool
+
+func (c dirCache) files(dir string) map[string]bool {
+	if names, ok := c[dir]; ok {
+		return names
+	}
+	names := map[string]bool{}
+	if entries, err := os.ReadDir(dir); err == nil {
+		for _, e := range entries {
+			if !e.IsDir() {
+				names[e.Name()] = true
+			}
+		}
+	}
+	c[dir] = names
+	return names
+}
+
+func headerDialect(path string, cfg languages.LangConfig, dirs dirCache) (languages.LangConfig, string) {
+	cpp, ok := languages.Get(".cpp")
+	if !ok {
+		return cfg, ""
+	}
+	if f, err := os.Open(path); err == nil {
+		src, _ := io.ReadAll(io.LimitReader(f, headerSize))
+		f.Close()
+		for _, m := range cppMarkers {
+			if m.re.Match(src) {
+				return cpp, m.name
+			}
+		}
+	}
+
+	names := dirs.files(filepath.Dir(path))
+	stem := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	for _, ext := range cppSourceExts {
+		if names[stem+ext] {
+			return cpp, "sibling " + stem + ext
+		}
+	}
+	for _, ext := range cSourceExts {
+		if names[stem+ext] {
+			return cfg, "sibling " + stem + ext
+		}
+	}
+
+	var sawCpp, sawC bool
+	for name := range names {
+		ext := filepath.Ext(name)
+		sawCpp = sawCpp || contains(cppSourceExts, ext)
+		sawC = sawC || contains(cSourceExts, ext)
+	}
+	switch {
+	case sawCpp && !sawC:
+		return cpp, "C++ sources in directory"
+	case sawC:
+		return cfg, "C sources in directory"
+	}
+	return cfg, "default"
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
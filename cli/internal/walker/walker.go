@@ -1,35 +1,71 @@
 package walker
 
 import (
+	"bytes"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 
 	"github.com/KashifKhn/remove-comments/cli/internal/languages"
 	"github.com/boyter/gocodewalker"
 )
 
+const sniffSize = 8 * 1024
+
 type FileEntry struct {
-	Path string
-	Ext  string
+	Path   string
+	Ext    string
+	Lang   languages.LangConfig
+	Reason string
+}
+
+type Mapping struct {
+	Glob string
 	Lang languages.LangConfig
 }
 
-func Walk(root string, langFilter string, maxFileSize int64, excludePatterns []string) ([]FileEntry, []error) {
+func ParseMappings(specs []string) ([]Mapping, error) {
+	var mappings []Mapping
+	for _, spec := range specs {
+		i := strings.LastIndex(spec, "=")
+		if i <= 0 || i == len(spec)-1 {
+			return nil, fmt.Errorf("map %q: want GLOB=LANG", spec)
+		}
+		glob, name := spec[:i], spec[i+1:]
+		cfg, ok := languages.ByName(name)
+		if !ok && strings.HasPrefix(name, ".") {
+			cfg, ok = languages.Get(name)
+		}
+		if !ok {
+			return nil, fmt.Errorf("map %q: unknown language %q", spec, name)
+		}
+		mappings = append(mappings, Mapping{Glob: glob, Lang: cfg})
+	}
+	return mappings, nil
+}
+
+func Walk(root string, langFilter string, maxFileSize int64, excludePatterns []string, mappings []Mapping) ([]FileEntry, []error) {
+	return WalkWith(root, langFilter, maxFileSize, excludePatterns, mappings, nil)
+}
+
+func WalkWith(root string, langFilter string, maxFileSize int64, excludePatterns []string, mappings []Mapping, scope func(path string) (int64, bool)) ([]FileEntry, []error) {
 	info, err := os.Stat(root)
 	if err != nil {
 		return nil, []error{err}
 	}
 	if !info.IsDir() {
-		return walkSingleFile(root, langFilter, maxFileSize, excludePatterns)
+		return walkSingleFile(root, langFilter, maxFileSize, excludePatterns, mappings)
 	}
 
 	queue := make(chan *gocodewalker.File, 512)
 
-	exts := buildAllowList(langFilter)
-
 	fw := gocodewalker.NewFileWalker(root, queue)
-	fw.AllowListExtensions = exts
 	fw.ExcludeDirectory = []string{".git", "node_modules", "vendor", ".idea", ".vscode"}
+	fw.ExcludeDirectoryRegex = []*regexp.Regexp{regexp.MustCompile(`^\.`)}
+	fw.IncludeHidden = true
 
 	var walkErr error
 	fw.SetErrorHandler(func(e error) bool {
@@ -43,34 +79,47 @@ func Walk(root string, langFilter string, maxFileSize int64, excludePatterns []s
 
 	var entries []FileEntry
 	var errs []error
+	dirs := dirCache{}
 
 	for f := range queue {
 		if matchesAny(f.Location, excludePatterns) {
 			continue
 		}
+		limit := maxFileSize
+		if scope != nil {
+			var ok bool
+			if limit, ok = scope(f.Location); !ok {
+				continue
+			}
+		}
 
-		ext := filepath.Ext(f.Filename)
-		cfg, ok := languages.Get(ext)
-		if !ok {
+		fi, statErr := os.Stat(f.Location)
+		if statErr != nil {
+			errs = append(errs, statErr)
 			continue
 		}
-		if langFilter != "" && cfg.Name != langFilter {
+		if !fi.Mode().IsRegular() || limit > 0 && fi.Size() > limit {
 			continue
 		}
 
-		fi, statErr := os.Stat(f.Location)
-		if statErr != nil {
-			errs = append(errs, statErr)
+		ext := filepath.Ext(f.Filename)
+		rel, relErr := filepath.Rel(root, f.Location)
+		if relErr != nil {
+			rel = f.Location
+		}
+		cfg, why, ok := detect(f.Location, rel, strings.HasPrefix(f.Filename, "."), mappings, dirs)
+		if !ok {
 			continue
 		}
-		if maxFileSize > 0 && fi.Size() > maxFileSize {
+		if langFilter != "" && cfg.Name != langFilter {
 			continue
 		}
 
 		entries = append(entries, FileEntry{
-			Path: f.Location,
-			Ext:  ext,
-			Lang: cfg,
+			Path:   f.Location,
+			Ext:    ext,
+			Lang:   cfg,
+			Reason: why,
 		})
 	}
 
@@ -81,26 +130,88 @@ func Walk(root string, langFilter string, maxFileSize int64, excludePatterns []s
 	return entries, errs
 }
 
-func walkSingleFile(path string, langFilter string, maxFileSize int64, excludePatterns []string) ([]FileEntry, []error) {
+func walkSingleFile(path string, langFilter string, maxFileSize int64, excludePatterns []string, mappings []Mapping) ([]FileEntry, []error) {
 	if matchesAny(path, excludePatterns) {
 		return nil, nil
 	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, []error{err}
+	}
+	if maxFileSize > 0 && fi.Size() > maxFileSize {
+		return nil, nil
+	}
 	ext := filepath.Ext(path)
-	cfg, ok := languages.Get(ext)
+	cfg, why, ok := Detect(path, path, false, mappings)
 	if !ok {
 		return nil, nil
 	}
 	if langFilter != "" && cfg.Name != langFilter {
 		return nil, nil
 	}
-	fi, err := os.Stat(path)
+	return []FileEntry{{Path: path, Ext: ext, Lang: cfg, Reason: why}}, nil
+}
+
+func Detect(path, rel string, hidden bool, mappings []Mapping) (languages.LangConfig, string, bool) {
+	return detect(path, rel, hidden, mappings, dirCache{})
+}
+
+func detect(path, rel string, hidden bool, mappings []Mapping, dirs dirCache) (languages.LangConfig, string, bool) {
+	for i := len(mappings) - 1; i >= 0; i-- {
+		m := mappings[i]
+		if matchesAny(filepath.ToSlash(rel), []string{m.Glob}) || matchesAny(path, []string{m.Glob}) {
+			return m.Lang, "map " + m.Glob, true
+		}
+	}
+	if cfg, ok := languages.ForFilename(path); ok {
+		return cfg, "filename", true
+	}
+	if hidden {
+		return languages.LangConfig{}, "", false
+	}
+	ext := filepath.Ext(path)
+	if cfg, ok := languages.Get(ext); ok {
+		if ext == ".h" && cfg.Name == "c" && !languages.Aliased(ext) {
+			cfg, why := headerDialect(path, cfg, dirs)
+			return cfg, why, true
+		}
+		return cfg, "", true
+	}
+	if ext != "" {
+		return languages.LangConfig{}, "", false
+	}
+	return sniff(path)
+}
+
+func sniff(path string) (languages.LangConfig, string, bool) {
+	f, err := os.Open(path)
 	if err != nil {
-		return nil, []error{err}
+		return languages.LangConfig{}, "", false
 	}
-	if maxFileSize > 0 && fi.Size() > maxFileSize {
-		return nil, nil
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || !info.Mode().IsRegular() || info.Size() == 0 {
+		return languages.LangConfig{}, "", false
+	}
+	head := make([]byte, sniffSize)
+	n, _ := io.ReadFull(f, head)
+	head = head[:n]
+	if bytes.IndexByte(head, 0) >= 0 {
+		return languages.LangConfig{}, "", false
+	}
+	if info.Size() > sniffSize {
+		offset := info.Size() - sniffSize
+		if offset > sniffSize {
+			head = append(head, '\n')
+		} else {
+			offset = sniffSize
+		}
+		tail := make([]byte, info.Size()-offset)
+		m, _ := f.ReadAt(tail, offset)
+		head = append(head, tail[:m]...)
 	}
-	return []FileEntry{{Path: path, Ext: ext, Lang: cfg}}, nil
+	return languages.Detect(head)
 }
 
 func matchesAny(path string, patterns []string) bool {
@@ -164,26 +275,3 @@ func min(a, b int) int {
 	}
 	return b
 }
-
-func buildAllowList(langFilter string) []string {
-	var source []string
-	if langFilter == "" {
-		source = languages.Supported()
-	} else {
-		for _, ext := range languages.Supported() {
-			cfg, ok := languages.Get(ext)
-			if ok && cfg.Name == langFilter {
-				source = append(source, ext)
-			}
-		}
-	}
-	exts := make([]string, 0, len(source))
-	for _, ext := range source {
-		if len(ext) > 1 && ext[0] == '.' {
-			exts = append(exts, ext[1:])
-		} else {
-			exts = append(exts, ext)
-		}
-	}
-	return exts
-}
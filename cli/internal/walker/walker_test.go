@@ -4,7 +4,10 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 	"testing"
+
+	"github.com/KashifKhn/remove-comments/cli/internal/languages"
 )
 
 func TestWalk_ReturnsOnlySupportedExtensions(t *testing.T) {
@@ -38,7 +41,7 @@ func TestWalk_ReturnsOnlySupportedExtensions(t *testing.T) {
 		}
 	}
 
-	entries, errs := Walk(dir, "", 0, nil)
+	entries, errs := Walk(dir, "", 0, nil, nil)
 	if len(errs) > 0 {
 		t.Fatalf("unexpected errors: %v", errs)
 	}
@@ -84,7 +87,7 @@ func TestWalk_RespectsGitignore(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	entries, errs := Walk(dir, "", 0, nil)
+	entries, errs := Walk(dir, "", 0, nil, nil)
 	if len(errs) > 0 {
 		t.Fatalf("unexpected errors: %v", errs)
 	}
@@ -120,7 +123,7 @@ func TestWalk_LangFilter(t *testing.T) {
 		}
 	}
 
-	entries, errs := Walk(dir, "go", 0, nil)
+	entries, errs := Walk(dir, "go", 0, nil, nil)
 	if len(errs) > 0 {
 		t.Fatalf("unexpected errors: %v", errs)
 	}
@@ -152,7 +155,7 @@ func TestWalk_MaxFileSizeExcludes(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	entries, errs := Walk(dir, "", 100, nil)
+	entries, errs := Walk(dir, "", 100, nil, nil)
 	if len(errs) > 0 {
 		t.Fatalf("unexpected errors: %v", errs)
 	}
@@ -181,7 +184,7 @@ func TestWalk_SingleFile(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	entries, errs := Walk(path, "", 0, nil)
+	entries, errs := Walk(path, "", 0, nil, nil)
 	if len(errs) > 0 {
 		t.Fatalf("unexpected errors: %v", errs)
 	}
@@ -193,6 +196,168 @@ func TestWalk_SingleFile(t *testing.T) {
 	}
 }
 
+func TestWalk_MatchesFilenames(t *testing.T) {
+	dir := t.TempDir()
+	for _, f := range []string{"Gemfile", "Rakefile", "Makefile", "tasks.rake"} {
+		if err := os.WriteFile(filepath.Join(dir, f), []byte("# x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, errs := Walk(dir, "ruby", 0, nil, nil)
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, filepath.Base(e.Path))
+	}
+	sort.Strings(names)
+	if got := strings.Join(names, ","); got != "Gemfile,Rakefile,tasks.rake" {
+		t.Errorf("got %s", got)
+	}
+
+	entries, _ = Walk(filepath.Join(dir, "Gemfile"), "", 0, nil, nil)
+	if len(entries) != 1 || entries[0].Lang.Name != "ruby" {
+		t.Errorf("expected Gemfile to be walked as ruby, got %+v", entries)
+	}
+}
+
+func TestWalk_MatchesDockerfiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, f := range []string{"Dockerfile", "Dockerfile.dev", "api.Dockerfile", "Containerfile", "Dockerfile.dockerignore", "docker-compose.yml"} {
+		if err := os.WriteFile(filepath.Join(dir, f), []byte("FROM alpine"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, errs := Walk(dir, "dockerfile", 0, nil, nil)
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, filepath.Base(e.Path))
+	}
+	sort.Strings(names)
+	if got := strings.Join(names, ","); got != "Containerfile,Dockerfile,Dockerfile.dev,api.Dockerfile" {
+		t.Errorf("got %s", got)
+	}
+}
+
+func TestWalk_DetectsExtensionlessFiles(t *testing.T) {
+	dir := t.TempDir()
+	long := strings.Repeat("x = 1\n", 3000)
+	files := map[string]string{
+		"bin/deploy":      "#!/usr/bin/env bash\necho hi\n",
+		"bin/tool":        "#!/usr/bin/env -S python3.12 -u\nprint(1)\n",
+		"bin/serve":       "#!/usr/local/bin/node\nconsole.log(1)\n",
+		"bin/task":        "# -*- mode: ruby -*-\nputs 1\n",
+		"bin/long":        long + "# vim: set ft=python:\n",
+		"bin/plain":       "just some text\n",
+		"bin/perl":        "#!/usr/bin/perl\nprint 1;\n",
+		"bin/blob":        "#!/bin/sh\x00\x01",
+		"ci/Jenkinsfile":  "node {}\n",
+		".zshrc":          "alias x=y\n",
+		".eslintrc.js":    "module.exports = {}\n",
+		".config/.bashrc": "alias x=y\n",
+		"notes.txt":       "#!/bin/sh\n",
+		"Makefile":        "all:\n\techo\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, errs := Walk(dir, "", 0, nil, nil)
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	got := map[string]string{}
+	for _, e := range entries {
+		rel, _ := filepath.Rel(dir, e.Path)
+		got[filepath.ToSlash(rel)] = e.Lang.Name
+	}
+	want := map[string]string{
+		"bin/deploy":     "bash",
+		"bin/tool":       "python",
+		"bin/serve":      "javascript",
+		"bin/task":       "ruby",
+		"bin/long":       "python",
+		"ci/Jenkinsfile": "groovy",
+		".zshrc":         "bash",
+	}
+	if len(got) != len(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	for name, lang := range want {
+		if got[name] != lang {
+			t.Errorf("%s: got %q, want %q", name, got[name], lang)
+		}
+	}
+}
+
+func TestWalk_Mappings(t *testing.T) {
+	dir := t.TempDir()
+	for _, f := range []string{"scripts/build", "scripts/run.txt", "main.py", ".eslintrc.js"} {
+		path := filepath.Join(dir, f)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mappings, err := ParseMappings([]string{"scripts/*=python", "*.py=bash", "main.py=.rb", ".eslintrc.js=javascript"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, errs := Walk(dir, "", 0, nil, mappings)
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	got := map[string]string{}
+	for _, e := range entries {
+		rel, _ := filepath.Rel(dir, e.Path)
+		got[filepath.ToSlash(rel)] = e.Lang.Name
+	}
+	want := map[string]string{
+		"scripts/build":   "python",
+		"scripts/run.txt": "python",
+		"main.py":         "ruby",
+		".eslintrc.js":    "javascript",
+	}
+	for name, lang := range want {
+		if got[name] != lang {
+			t.Errorf("%s: got %q, want %q", name, got[name], lang)
+		}
+	}
+}
+
+func TestParseMappings_Errors(t *testing.T) {
+	tests := []struct {
+		spec string
+		want string
+	}{
+		{"bin/*", "want GLOB=LANG"},
+		{"=bash", "want GLOB=LANG"},
+		{"bin/*=", "want GLOB=LANG"},
+		{"bin/*=cobol", `unknown language "cobol"`},
+	}
+	for _, tt := range tests {
+		_, err := ParseMappings([]string{tt.spec})
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseMappings(%q): got %v, want error containing %q", tt.spec, err, tt.want)
+		}
+	}
+}
+
 func TestWalk_SingleFileUnsupportedExtension(t *testing.T) {
 	dir := t.TempDir()
 	path := filepath.Join(dir, "README.md")
@@ -200,7 +365,7 @@ func TestWalk_SingleFileUnsupportedExtension(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	entries, errs := Walk(path, "", 0, nil)
+	entries, errs := Walk(path, "", 0, nil, nil)
 	if len(errs) > 0 {
 		t.Fatalf("unexpected errors: %v", errs)
 	}
@@ -210,7 +375,7 @@ func TestWalk_SingleFileUnsupportedExtension(t *testing.T) {
 }
 
 func TestWalk_PathDoesNotExist(t *testing.T) {
-	_, errs := Walk("/nonexistent/path/that/does/not/exist", "", 0, nil)
+	_, errs := Walk("/nonexistent/path/that/does/not/exist", "", 0, nil, nil)
 	if len(errs) == 0 {
 		t.Error("expected an error for non-existent path")
 	}
@@ -236,7 +401,7 @@ func TestWalk_NestedGitignore(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	entries, errs := Walk(dir, "", 0, nil)
+	entries, errs := Walk(dir, "", 0, nil, nil)
 	if len(errs) > 0 {
 		t.Fatalf("unexpected errors: %v", errs)
 	}
@@ -267,7 +432,7 @@ func TestWalk_NestedGitignore(t *testing.T) {
 func TestWalk_EmptyDirectory(t *testing.T) {
 	dir := t.TempDir()
 
-	entries, errs := Walk(dir, "", 0, nil)
+	entries, errs := Walk(dir, "", 0, nil, nil)
 	if len(errs) > 0 {
 		t.Fatalf("unexpected errors: %v", errs)
 	}
@@ -289,7 +454,7 @@ func TestWalk_ExcludesNodeModules(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	entries, errs := Walk(dir, "", 0, nil)
+	entries, errs := Walk(dir, "", 0, nil, nil)
 	if len(errs) > 0 {
 		t.Fatalf("unexpected errors: %v", errs)
 	}
@@ -311,7 +476,7 @@ func TestWalk_ExcludeByBasenameGlob(t *testing.T) {
 		}
 	}
 
-	entries, errs := Walk(dir, "", 0, []string{"*.g.dart"})
+	entries, errs := Walk(dir, "", 0, []string{"*.g.dart"}, nil)
 	if len(errs) > 0 {
 		t.Fatalf("unexpected errors: %v", errs)
 	}
@@ -334,6 +499,43 @@ func TestWalk_ExcludeByBasenameGlob(t *testing.T) {
 	}
 }
 
+func TestWalkWith_Scope(t *testing.T) {
+	dir := t.TempDir()
+	for _, f := range []string{"app.go", "notes.md", "gen.g.dart", filepath.Join("sub", "big.go"), filepath.Join("skip", "b.go")} {
+		path := filepath.Join(dir, f)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("package x\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var visited []string
+	entries, errs := WalkWith(dir, "", 0, []string{"*.g.dart"}, nil, func(path string) (int64, bool) {
+		rel, _ := filepath.Rel(dir, path)
+		rel = filepath.ToSlash(rel)
+		visited = append(visited, rel)
+		switch {
+		case strings.HasPrefix(rel, "skip/"):
+			return 0, false
+		case strings.HasPrefix(rel, "sub/"):
+			return 4, true
+		}
+		return 0, true
+	})
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if len(entries) != 1 || filepath.Base(entries[0].Path) != "app.go" {
+		t.Errorf("got %v, want only app.go", entries)
+	}
+	sort.Strings(visited)
+	if got := strings.Join(visited, ","); got != "app.go,notes.md,skip/b.go,sub/big.go" {
+		t.Errorf("visited %s", got)
+	}
+}
+
 func TestWalk_ExcludeMultiplePatterns(t *testing.T) {
 	dir := t.TempDir()
 
@@ -344,7 +546,7 @@ func TestWalk_ExcludeMultiplePatterns(t *testing.T) {
 		}
 	}
 
-	entries, errs := Walk(dir, "", 0, []string{"*.g.dart", "*_test.go"})
+	entries, errs := Walk(dir, "", 0, []string{"*.g.dart", "*_test.go"}, nil)
 	if len(errs) > 0 {
 		t.Fatalf("unexpected errors: %v", errs)
 	}
@@ -370,7 +572,7 @@ func TestWalk_ExcludeNilPatternsIncludesAll(t *testing.T) {
 		}
 	}
 
-	entries, errs := Walk(dir, "", 0, nil)
+	entries, errs := Walk(dir, "", 0, nil, nil)
 	if len(errs) > 0 {
 		t.Fatalf("unexpected errors: %v", errs)
 	}
@@ -404,3 +606,104 @@ func TestMatchesAny(t *testing.T) {
 		})
 	}
 }
+
+func TestWalk_HeaderDialect(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"ns/a.h":          "namespace a { int f(); }\n",
+		"tpl/a.h":         "template <typename T> T id(T x);\n",
+		"cls/a.h":         "#pragma once\nclass Widget;\n",
+		"ext/a.h":         "extern \"C++\" {\nint f();\n}\n",
+		"std/a.h":         "#include <vector>\n",
+		"clang/a.h":       "#ifdef __cplusplus\nextern \"C\" {\n#endif\n#include <stdio.h>\nstruct s { int x; };\n",
+		"sib/a.h":         "int f(void);\n",
+		"sib/a.cc":        "int f() { return 0; }\n",
+		"csib/a.h":        "int f(void);\n",
+		"csib/a.c":        "int f(void) { return 0; }\n",
+		"csib/b.cpp":      "int g() { return 0; }\n",
+		"cppdir/a.h":      "int f(void);\n",
+		"cppdir/main.cpp": "int main() {}\n",
+		"lone/a.h":        "int f(void);\n",
+		"mapped/a.h":      "namespace a {}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mappings, err := ParseMappings([]string{"mapped/*.h=c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, errs := Walk(dir, "", 0, nil, mappings)
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	got := map[string]string{}
+	for _, e := range entries {
+		rel, _ := filepath.Rel(dir, e.Path)
+		if filepath.Ext(rel) == ".h" {
+			got[filepath.ToSlash(rel)] = e.Lang.Name + " " + e.Reason
+		}
+	}
+	want := map[string]string{
+		"ns/a.h":     "cpp namespace",
+		"tpl/a.h":    "cpp template<",
+		"cls/a.h":    "cpp class",
+		"ext/a.h":    `cpp extern "C++"`,
+		"std/a.h":    "cpp C++ standard header",
+		"clang/a.h":  "c default",
+		"sib/a.h":    "cpp sibling a.cc",
+		"csib/a.h":   "c sibling a.c",
+		"cppdir/a.h": "cpp C++ sources in directory",
+		"lone/a.h":   "c default",
+		"mapped/a.h": "c map mapped/*.h",
+	}
+	for name, w := range want {
+		if got[name] != w {
+			t.Errorf("%s: got %q, want %q", name, got[name], w)
+		}
+	}
+}
+
+func TestHeaderDialect_ReadsLimitedInput(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"late/a.h":   strings.Repeat("int x;\n", headerSize/7+1) + "namespace a {}\n",
+		"cached/a.h": "int f(void);\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c, _ := languages.Get(".h")
+	dirs := dirCache{filepath.Join(dir, "cached"): {"a.h": true, "main.cpp": true}}
+
+	tests := []struct {
+		file, want string
+	}{
+		{"late/a.h", "c default"},
+		{"cached/a.h", "cpp C++ sources in directory"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			cfg, why := headerDialect(filepath.Join(dir, tt.file), c, dirs)
+			if got := cfg.Name + " " + why; got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+	if names := dirs[filepath.Join(dir, "late")]; !names["a.h"] {
+		t.Errorf("directory listing was not cached: %v", names)
+	}
+}